@@ -13,69 +13,199 @@ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 See the License for the specific language governing permissions and
 limitations under the License.
 */
+
 package cmd
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/kmpm/gopr/lib/fsutil"
+	"github.com/kmpm/gopr/lib/scaffold"
+	"github.com/kmpm/gopr/lib/vcs"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	addOpts         addOptions
+	addInstallTools bool
 )
 
+//addOptions are the ways a new project can be populated
+type addOptions struct {
+	// Template to render into the project directory
+	Template string
+	// Module path for the template, the project name if empty
+	Module string
+	// Git is the url of a repository to clone into SrcDir
+	Git string
+	// Branch to check out instead of the default branch
+	Branch string
+	// SrcDir is where the repository is cloned, relative to the project
+	SrcDir string
+}
+
 // addCmd represents the add command
 var addCmd = &cobra.Command{
-	Use:   "add",
+	Use:   "add <project>",
 	Short: "Add/Create a go project environment",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Long: `Create a new project with an empty GOPATH and a default project.yaml.
+
+With --template the project is pre-populated from a template. A template is
+a directory whose files are copied into the project directory. File names
+and contents are executed as Go text/template with these fields
+
+  .Name         the project name
+  .ProjectPath  the project directory
+  .GoPath       the GOPATH of the project
+  .Module       the module path given with --module, defaults to the
+                module of the --git repository or the name
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+A ` + scaffold.TemplateSuffix + ` suffix is removed from file names. A project.yaml in the
+template is merged into the default configuration, so it can set env,
+goprivate, goversion and a list of tools to 'go install' with
+--install-tools.
+
+User templates are directories in ` + filepath.Join("<root>", templatesDir) + ` and take
+precedence over the built-in templates: ` + strings.Join(scaffold.Builtin(), ", ") + `
+
+With --git the repository is cloned with the system git into
+<srcdir>/<repository name> in the project directory, srcdir defaults to
+'src' and can be set in the configuration file. The GOPRIVATE pattern for
+the remote host is added to the project, host/owner for the public hosts
+` + strings.Join(vcs.PublicHosts, ", ") + ` and the whole host for others.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			er("You must provide a project name", nil)
 			return
 		}
-		projectName := args[0]
+		opts := addOpts
+		opts.SrcDir = viper.GetString("srcdir")
+		cfg, err := addProject(args[0], opts)
+		exitOn("Can not create project", err)
 
-		cfg, err := shellCfgSet(projectName)
-		if err != nil {
-			exitOn("Error getting shell configuration", err)
+		if addInstallTools {
+			err = installTools(cfg.ProjectName)
+			exitOn("Could not install tools", err)
+		}
+	},
+}
+
+//addProject creates the project directory, GOPATH and project.yaml of
+//projectName and populates it as requested in opts
+func addProject(projectName string, opts addOptions) (*shellConfig, error) {
+	if err := checkProjectName(projectName); err != nil {
+		return nil, err
+	}
+	cfg := projectPaths(projectName)
+	if _, err := os.Stat(cfg.ProjectPath); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("project path '%s' exists", cfg.ProjectPath)
+	}
+
+	var tmpl fs.FS
+	var err error
+	if opts.Template != "" {
+		if tmpl, err = scaffold.Find(templatesRoot(), opts.Template); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, opts.Template)
 		}
-		if _, err := os.Stat(cfg.ProjectPath); !os.IsNotExist(err) {
-			fmt.Printf("Project path '%s' exists\n", cfg.ProjectPath)
-			os.Exit(1)
+	}
+	var remote *vcs.Remote
+	if opts.Git != "" {
+		if remote, err = vcs.Parse(opts.Git); err != nil {
+			return nil, err
 		}
-		fmt.Println("Creating", cfg.GoPath)
-		err = os.MkdirAll(cfg.GoPath, os.ModeDir|os.ModePerm)
-		if err != nil {
-			fmt.Printf("Error creating %s: %+v\n", cfg.GoPath, err)
-			os.Exit(1)
+	}
+	if opts.Module == "" {
+		opts.Module = projectName
+		if remote != nil {
+			opts.Module = remote.Module()
 		}
+	}
 
-		// if err = touch(cfg.ConfigFile); err != nil {
-		// 	fmt.Printf("Could not touch %s: %v\n", cfg.ConfigFile, err)
-		// 	os.Exit(1)
-		// }
-
-		pc, _ := cfg.GetProjectConfig()
-		// pc.Env["DOCKER_HOST"] = "ssh://anonymous@localhost"
+	fmt.Println("Creating", cfg.GoPath)
+	if err = os.MkdirAll(cfg.GoPath, os.ModeDir|os.ModePerm); err != nil {
+		return nil, err
+	}
+	pc, _ := cfg.GetProjectConfig()
+	if tmpl != nil {
+		err = scaffold.Render(tmpl, cfg.ProjectPath, scaffold.Data{
+			Name:        projectName,
+			ProjectPath: cfg.ProjectPath,
+			GoPath:      cfg.GoPath,
+			Module:      opts.Module,
+		})
+		if err == nil {
+			// a project.yaml from the template only holds what it changes
+			if tc, rerr := readProjectConfig(cfg.ConfigFile); rerr == nil {
+				pc.Overlay(tc)
+			} else if !os.IsNotExist(rerr) {
+				err = rerr
+			}
+		}
+	}
+	if err == nil && remote != nil {
+		src := filepath.Join(cfg.ProjectPath, opts.SrcDir, remote.Name())
+		if filepath.IsAbs(opts.SrcDir) {
+			src = filepath.Join(opts.SrcDir, remote.Name())
+		}
+		fmt.Println("Cloning", opts.Git, "into", src)
+		if err = vcs.Clone(opts.Git, src, opts.Branch); err == nil {
+			pc.GoPrivate = vcs.AddPattern(pc.GoPrivate, remote.PrivatePattern())
+		}
+	}
+	if err == nil {
 		err = writeProjectConfig(pc, cfg.ConfigFile)
-		exitOn("Could not create project configuration", err)
-	},
+	}
+	if err != nil {
+		fsutil.RemoveAll(cfg.ProjectPath)
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//installTools runs 'go install' in the environment of the project for
+//every tool listed in its project.yaml
+func installTools(projectName string) error {
+	cfg, err := loadProject(projectName)
+	if err == nil {
+		err = cfg.ResolveSecrets()
+	}
+	if err != nil {
+		return err
+	}
+	pc, err := readMergedConfig(projectName)
+	if err != nil {
+		return err
+	}
+	goCmd, err := lookPath("go", cfg.Path)
+	if err != nil {
+		return err
+	}
+	for _, tool := range pc.Tools {
+		fmt.Println("Installing", tool)
+		code, err := runCommand(goCmd, []string{"install", tool}, cfg.Environ(os.Environ()))
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("go install %s exited with %d", tool, code)
+		}
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(addCmd)
 
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// addCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// addCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	addCmd.Flags().StringVarP(&addOpts.Template, "template", "t", "", "populate the project from a template")
+	addCmd.Flags().StringVar(&addOpts.Module, "module", "", "module path for templates (default the project name)")
+	addCmd.Flags().StringVar(&addOpts.Git, "git", "", "clone the repository at url into the project")
+	addCmd.Flags().StringVar(&addOpts.Branch, "branch", "", "branch to check out with --git")
+	addCmd.Flags().String("srcdir", "src", "directory in the project to clone into")
+	viper.BindPFlag("srcdir", addCmd.Flags().Lookup("srcdir"))
+	addCmd.Flags().BoolVar(&addInstallTools, "install-tools", false, "go install the tools listed in project.yaml")
 }
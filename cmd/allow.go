@@ -0,0 +1,95 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kmpm/gopr/lib/project"
+	"github.com/spf13/cobra"
+)
+
+// allowCmd represents the allow command
+var allowCmd = &cobra.Command{
+	Use:   "allow [path]",
+	Short: "Let gopr use a .gopr.yaml",
+	Long: `Trust the settings of a .gopr.yaml so that gopr uses it. A .gopr.yaml
+can set variables, go settings and PATH, so one that came with a cloned
+repository is only used once you allowed it. Until then the shell hooks
+pass over it, and env, shell, info, direnv export, config and secrets
+refuse to take the project from it.
+
+The file is allowed with its current content, after any change it has to
+be allowed again. Without a path the .gopr.yaml of the current directory
+or one of its parents is allowed. The list is kept in ~/.gopr-allowed.txt.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := allowTarget(args)
+		exitOn("Can not find the file to allow", err)
+		list, err := project.ReadAllowList(allowListFile())
+		exitOn("Could not read the allowed files", err)
+		exitOn("Could not allow "+file, list.Allow(file))
+		fmt.Println("Allowed", file)
+	},
+}
+
+var denyCmd = &cobra.Command{
+	Use:   "deny [path]",
+	Short: "Stop gopr from using a .gopr.yaml",
+	Long: `Remove a .gopr.yaml from the files allowed with 'gopr allow'. Without a
+path the .gopr.yaml of the current directory or one of its parents is
+used.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := allowTarget(args)
+		exitOn("Can not find the file to deny", err)
+		list, err := project.ReadAllowList(allowListFile())
+		exitOn("Could not read the allowed files", err)
+		exitOn("Could not deny "+file, list.Deny(file))
+		fmt.Println("Denied", file)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(allowCmd)
+	rootCmd.AddCommand(denyCmd)
+}
+
+//allowListFile is where the allowed local configuration files are kept
+func allowListFile() string {
+	return filepath.Join(userHome, ".gopr-allowed.txt")
+}
+
+//allowTarget returns the .gopr.yaml given in args, as the file or the
+//directory it is in, or the one found from the current directory
+func allowTarget(args []string) (string, error) {
+	if len(args) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		return project.FindLocalConfig(wd, globalConfigFiles()...)
+	}
+	f := args[0]
+	if info, err := os.Stat(f); err != nil {
+		return "", err
+	} else if info.IsDir() {
+		f = filepath.Join(f, project.LocalConfigFile)
+	}
+	return filepath.Abs(f)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kmpm/gopr/lib/fsutil"
+	"github.com/kmpm/gopr/lib/project"
+	"github.com/spf13/cobra"
+)
+
+var cloneModCache string
+
+// cloneCmd represents the clone command
+var cloneCmd = &cobra.Command{
+	Use:   "clone <project> <new project>",
+	Short: "Create a project with the settings of another",
+	Long: `Create a new project with a copy of the project.yaml of another one.
+Absolute paths to the source project in its env are changed to the new
+project.
+
+The module cache in go/pkg/mod can be brought along with --modcache so the
+new project does not have to download the modules again
+
+  none      start with an empty module cache (default)
+  hardlink  link the files, fast and uses no space but requires the same
+            file system, which is always the case inside the projects root
+  reflink   copy on write clones on file systems that support it, like
+            btrfs and xfs
+  copy      copy every file`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		srcName, dstName := args[0], args[1]
+
+		found, err := projectExists(srcName)
+		exitOn("Can not list projects", err)
+		if !found {
+			exitOn("Invalid project", fmt.Errorf("project '%s' not in list", srcName))
+		}
+		exitOn("Invalid project name", checkProjectName(dstName))
+		if cloneModCache != "none" && cloneModCache != string(fsutil.Copy) &&
+			cloneModCache != string(fsutil.Hardlink) && cloneModCache != string(fsutil.Reflink) {
+			er(fmt.Sprintf("Invalid --modcache '%s', use none, hardlink, reflink or copy", cloneModCache), nil)
+		}
+
+		src, dst := projectPaths(srcName), projectPaths(dstName)
+		if _, err := os.Lstat(dst.ProjectPath); !os.IsNotExist(err) {
+			er(fmt.Sprintf("Project path '%s' exists", dst.ProjectPath), nil)
+		}
+
+		err = cloneProject(src, dst, cloneModCache)
+		if err != nil {
+			fsutil.RemoveAll(dst.ProjectPath)
+		}
+		if errors.Is(err, fsutil.ErrReflinkUnsupported) {
+			err = fmt.Errorf("%w by the file system, use hardlink or copy", err)
+		}
+		exitOn("Could not clone project", err)
+	},
+}
+
+//cloneProject creates dst with the configuration of src and, unless mode
+//is none, its module cache
+func cloneProject(src, dst *shellConfig, mode string) error {
+	fmt.Println("Creating", dst.GoPath)
+	if err := os.MkdirAll(dst.GoPath, os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(src.ConfigFile); os.IsNotExist(err) {
+		pc, _ := dst.GetProjectConfig()
+		if err := writeProjectConfig(pc, dst.ConfigFile); err != nil {
+			return err
+		}
+	} else {
+		doc, err := project.ReadConfigDocument(src.ConfigFile)
+		if err != nil {
+			return err
+		}
+		if _, err := doc.Config(); err != nil {
+			return fmt.Errorf("%s: %w", src.ConfigFile, err)
+		}
+		doc.RewritePaths(src.ProjectPath, dst.ProjectPath)
+		if err := doc.Write(dst.ConfigFile); err != nil {
+			return err
+		}
+	}
+
+	modcache := filepath.Join(src.GoPath, "pkg", "mod")
+	if _, err := os.Stat(modcache); mode == "none" || os.IsNotExist(err) {
+		return nil
+	}
+	fmt.Printf("Cloning module cache (%s)\n", mode)
+	if err := os.MkdirAll(filepath.Join(dst.GoPath, "pkg"), os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+	return fsutil.CopyTree(modcache, filepath.Join(dst.GoPath, "pkg", "mod"), fsutil.CopyMode(mode))
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().StringVar(&cloneModCache, "modcache", "none", "how to bring the module cache along: none, hardlink, reflink or copy")
+}
@@ -22,43 +22,115 @@ import (
 	"go/build"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/kmpm/gopr/lib/envfile"
+	"github.com/kmpm/gopr/lib/pathlist"
 	"github.com/kmpm/gopr/lib/project"
+	"github.com/kmpm/gopr/lib/secret"
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/kmpm/gopr/lib/toolchain"
+	"github.com/spf13/viper"
 )
 
 const (
 	projectConfigFile string = "project.yaml"
+	toolchainsDir     string = "toolchains"
+	templatesDir      string = "templates"
+	profilesDir       string = "profiles"
+	activatedFile     string = ".activated"
+	activeEnvVar      string = "GOPR_ACTIVE"
 )
 
+type envVar = envfile.Var
+
 type shellConfig struct {
-	Prefix      string
-	Delimiter   string
-	Suffix      string
-	Comment     string
+	Shell       shell.Shell
+	ProjectName string
 	ProjectPath string
 	ConfigFile  string
 	GoPath      string
+	GoRoot      string
+	GoVersion   string
 	GoPrivate   string
 	Go111Module string
-	Path        string
-	UsageHint   string
-	Env         map[string]string
+	// GoEnv are the other go environment variables set by the project
+	GoEnv     []envVar
+	Path      string
+	UsageHint string
+	Env       map[string]string
+	// Sources tells where the value of each variable came from
+	Sources map[string]string
+	// PathSources tells which file, or gopr, added or removed each PATH
+	// directory
+	PathSources map[string]string
+	// Extends are the projects and profiles the project extends, in the
+	// order they apply
+	Extends []string
+	// Files are the configuration files that were read, in the order they
+	// apply
+	Files []string
+	// config is the merged project config, kept to resolve secrets
+	config *project.Config
+	// pathLayers are the path settings of each file, in the order they
+	// apply, to fill PathSources
+	pathLayers []pathLayer
+}
+
+//pathLayer is the path setting of one configuration file
+type pathLayer struct {
+	file string
+	path project.PathList
 }
 
 var (
 	// ErrInvalidProjectName - The given name is not valid for projects
 	ErrInvalidProjectName = errors.New("invalid project name")
-	gitVersion            = "-DEV-"
-	appVersion            = "v0.0.0"
+	// ErrReservedProjectName - The name is used by gopr itself in projectsRoot
+	ErrReservedProjectName = errors.New("reserved project name")
+	// ErrUnknownParent - A name in extends is neither a project nor a profile
+	ErrUnknownParent = errors.New("no project or profile")
+	reservedNames    = []string{toolchainsDir, templatesDir, profilesDir}
+	gitVersion       = "-DEV-"
+	appVersion       = "v0.0.0"
 )
 
-func shellCfgSet(projectName string) (*shellConfig, error) {
+//shellSyntax returns a shellConfig with only the Shell used to generate
+//code and the usage hint set. Unknown shells get sh syntax.
+func shellSyntax() (*shellConfig, error) {
 	userShell, err := getShell(userShell)
 	if err != nil {
 		return nil, err
 	}
+	sh, err := shell.Get(userShell)
+	if err != nil {
+		sh, _ = shell.Get("sh")
+	}
+	shellCfg := &shellConfig{
+		Shell:     sh,
+		UsageHint: defaultUsageHinter.GenerateUsageHint(userShell, os.Args),
+	}
+	return shellCfg, nil
+}
+
+//useShell sets the syntax of the user's shell and the usage hint of
+//shellCfg, only the commands that print shell code need them
+func (shellCfg *shellConfig) useShell() error {
+	syntax, err := shellSyntax()
+	if err != nil {
+		return err
+	}
+	shellCfg.Shell = syntax.Shell
+	shellCfg.UsageHint = syntax.UsageHint
+	return nil
+}
+
+//projectPaths returns the shellConfig of projectName with the paths and
+//defaults set but no shell, for commands that never generate shell code
+func projectPaths(projectName string) *shellConfig {
+	shellCfg := &shellConfig{}
 	projectpath := filepath.Join(projectsRoot, projectName)
 	gopath := filepath.Join(projectpath, "go")
 	//get current
@@ -67,45 +139,187 @@ func shellCfgSet(projectName string) (*shellConfig, error) {
 		oldpath = build.Default.GOPATH
 	}
 
-	searchPath := os.Getenv("PATH")
-	newList := []string{
-		filepath.Join(gopath, "bin"),
+	// directories of the old GOPATH and of toolchains are left out
+	stale := append(pathlist.Split(oldpath), toolchainsRoot())
+	list := pathlist.Filter(pathlist.Split(os.Getenv("PATH")), func(p string) bool {
+		for _, dir := range stale {
+			if pathlist.Under(p, dir) {
+				return false
+			}
+		}
+		return true
+	})
+
+	shellCfg.Path = pathlist.Join(pathlist.Unique(append([]string{filepath.Join(gopath, "bin")}, list...)))
+	shellCfg.ProjectName = projectName
+	shellCfg.ProjectPath = projectpath
+	shellCfg.ConfigFile = filepath.Join(projectpath, projectConfigFile)
+	shellCfg.GoPath = gopath
+	shellCfg.GoPrivate = defaultGOPRIVATE
+	shellCfg.Go111Module = defaultGO111MODULE
+	shellCfg.Env = make(map[string]string)
+	shellCfg.Sources = map[string]string{
+		"GOPATH":      "root from " + settingSource("root"),
+		"GO111MODULE": settingSource("go111module"),
+		"GOPRIVATE":   settingSource("goprivate"),
+		"PATH":        "gopr",
+		activeEnvVar:  "gopr",
+	}
+	shellCfg.PathSources = map[string]string{filepath.Join(gopath, "bin"): "gopr"}
+	return shellCfg
+}
+
+//settingSource tells which layer the global setting key comes from. The
+//order is the one viper uses, a flag before an environment variable
+//before the configuration file.
+func settingSource(key string) string {
+	if f := rootCmd.PersistentFlags().Lookup(key); f != nil && f.Changed {
+		return "flag --" + key
+	}
+	if env, ok := settingEnv(key); ok {
+		return "env " + env
+	}
+	if viper.InConfig(key) {
+		return "config " + viper.ConfigFileUsed()
+	}
+	return "default"
+}
+
+//noteSources records file as the source of the settings c sets
+func (shellCfg *shellConfig) noteSources(c *project.Config, file string) {
+	for _, v := range c.GoEnv() {
+		shellCfg.Sources[v[0]] = file
+	}
+	if c.GoPrivate != "" {
+		shellCfg.Sources["GOPRIVATE"] = file
+	}
+	if c.GoVersion != "" {
+		shellCfg.Sources["GOROOT"] = file
+		shellCfg.notePathSource(file)
+	}
+	for k := range c.Env {
+		shellCfg.Sources[k] = file
+	}
+	if !c.Path.Empty() {
+		shellCfg.notePathSource(file)
+		shellCfg.pathLayers = append(shellCfg.pathLayers, pathLayer{file: file, path: c.Path})
+	}
+	for k := range c.PathLists {
+		shellCfg.Sources[k] = file
+	}
+}
+
+//notePathSource adds file to the sources of PATH, they are listed in the
+//order they apply
+func (shellCfg *shellConfig) notePathSource(file string) {
+	sources := strings.Split(shellCfg.Sources["PATH"], ", ")
+	if _, found := find(sources, file); !found {
+		shellCfg.Sources["PATH"] = strings.Join(append(sources, file), ", ")
 	}
-	list := strings.Split(searchPath, string(os.PathListSeparator))
-	for _, p := range list {
-		if !strings.Contains(p, oldpath) {
-			newList = append(newList, p)
+}
+
+//toolchainsRoot is the shared cache of go SDKs used by all projects
+func toolchainsRoot() string {
+	return filepath.Join(projectsRoot, toolchainsDir)
+}
+
+//checkProjectName returns an error if name can not be used for a new
+//project directory in projectsRoot
+func checkProjectName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return ErrInvalidProjectName
+	}
+	if _, reserved := find(reservedNames, name); reserved {
+		return ErrReservedProjectName
+	}
+	return nil
+}
+
+//markActivated records the time the project was last used in the
+//modification time of activatedFile. It is best effort and never fails.
+func markActivated(cfg *shellConfig) {
+	f := filepath.Join(cfg.ProjectPath, activatedFile)
+	now := time.Now()
+	if err := os.Chtimes(f, now, now); os.IsNotExist(err) {
+		touch(f)
+	}
+}
+
+//lastActivated returns when the project in projectPath was last
+//activated, the zero time if it never was
+func lastActivated(projectPath string) time.Time {
+	info, err := os.Stat(filepath.Join(projectPath, activatedFile))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+//templatesRoot returns the directory with user defined project templates
+func templatesRoot() string {
+	return filepath.Join(projectsRoot, templatesDir)
+}
+
+//profilesRoot returns the directory with profiles that projects can extend
+func profilesRoot() string {
+	return filepath.Join(projectsRoot, profilesDir)
+}
+
+//findParentConfig returns the file of a name in extends, the project.yaml
+//of the project or, if there is no such project, the profile
+//profilesRoot/name.yaml
+func findParentConfig(name string) (string, error) {
+	if found, err := projectExists(name); err != nil {
+		return "", err
+	} else if found {
+		return projectConfigPath(name), nil
+	}
+	if f := filepath.Join(profilesRoot(), name+".yaml"); exists(f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("%w called '%s' in %s", ErrUnknownParent, name, projectsRoot)
+}
+
+//extendingConfigs returns the config files of the projects and profiles
+//that extend name directly. Files that can not be read are skipped.
+func extendingConfigs(name string) ([]string, error) {
+	projects, err := projectList()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(projects))
+	for _, p := range projects {
+		if p != name {
+			files = append(files, projectConfigPath(p))
 		}
 	}
-	searchPath = strings.Join(newList, string(os.PathListSeparator))
+	profiles, _ := filepath.Glob(filepath.Join(profilesRoot(), "*.yaml"))
+	found := []string{}
+	for _, f := range append(files, profiles...) {
+		c, err := project.ReadConfig(f)
+		if err != nil {
+			continue
+		}
+		if _, ok := find(c.Extends, name); ok {
+			found = append(found, f)
+		}
+	}
+	return found, nil
+}
 
-	shellCfg := &shellConfig{
-		UsageHint:   defaultUsageHinter.GenerateUsageHint(userShell, os.Args),
-		Path:        searchPath,
-		ProjectPath: projectpath,
-		ConfigFile:  filepath.Join(projectpath, projectConfigFile),
-		GoPath:      gopath,
-		GoPrivate:   defaultGOPRIVATE,
-		Go111Module: defaultGO111MODULE,
-		Env:         make(map[string]string),
-		Prefix:      "export ",
-		Suffix:      "\"\n",
-		Delimiter:   "=\"",
-		Comment:     "#",
-	}
-
-	switch userShell {
-	case "powershell":
-		shellCfg.Prefix = "$Env:"
-		shellCfg.Suffix = "\"\n"
-		shellCfg.Delimiter = " = \""
-	case "cmd":
-		shellCfg.Prefix = "SET "
-		shellCfg.Suffix = "\n"
-		shellCfg.Delimiter = "="
-		shellCfg.Comment = "REM "
+//readMergedConfig reads the project.yaml of projectName with the configs
+//it extends merged underneath
+func readMergedConfig(projectName string) (*project.Config, error) {
+	layers, err := project.ReadLayers(projectName, findParentConfig)
+	if err != nil {
+		return nil, err
 	}
-	return shellCfg, nil
+	return project.Flatten(layers), nil
+}
+
+//projectConfigPath returns the location of project.yaml for projectName
+func projectConfigPath(projectName string) string {
+	return filepath.Join(projectsRoot, projectName, projectConfigFile)
 }
 
 func touch(filename string) error {
@@ -149,6 +363,141 @@ func projectExists(projectName string) (bool, error) {
 	return found, nil
 }
 
+//loadProject validates projectName and returns its shell configuration
+//merged with the settings in project.yaml and then any local overlays
+func loadProject(projectName string, overlays ...*project.LocalConfig) (*shellConfig, error) {
+	found, err := projectExists(projectName)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("project '%s' not in list", projectName)
+	}
+
+	cfg := projectPaths(projectName)
+	if err := upgradeProjectConfig(cfg.ConfigFile); err != nil {
+		return nil, err
+	}
+	var pc *project.Config
+	layers, err := project.ReadLayers(projectName, findParentConfig)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		// Merge always uses the goprivate of the project, it is empty
+		// unless a layer sets it
+		cfg.Sources["GOPRIVATE"] = "gopr"
+		for _, l := range layers {
+			cfg.noteSources(l.Config, l.File)
+			cfg.Files = append(cfg.Files, l.File)
+			if l.Name != projectName {
+				cfg.Extends = append(cfg.Extends, l.Name)
+			}
+		}
+		pc = project.Flatten(layers)
+	}
+	for _, o := range overlays {
+		if pc == nil {
+			pc, _ = cfg.GetProjectConfig()
+		}
+		pc.Overlay(&o.Config)
+		cfg.noteSources(&o.Config, o.File)
+		cfg.Files = append(cfg.Files, o.File)
+	}
+	if pc != nil {
+		if err := cfg.Merge(pc); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.GoVersion != "" && !toolchain.Installed(toolchainsRoot(), cfg.GoVersion) {
+		return nil, fmt.Errorf("toolchain %s is not installed, run 'gopr toolchain install %s'", cfg.GoVersion, cfg.GoVersion)
+	}
+	return cfg, nil
+}
+
+//projectFromArgs loads the project named in args or, when there are no
+//arguments, the one configured by a .gopr.yaml in the current directory
+//or one of its parents
+func projectFromArgs(args []string) (*shellConfig, error) {
+	switch len(args) {
+	case 1:
+		return loadProject(args[0])
+	case 0:
+		local, _, err := findLocalConfig()
+		if err != nil {
+			return nil, err
+		}
+		return loadProject(local.Project, local)
+	}
+	return nil, ErrInvalidProjectName
+}
+
+//findLocalConfig reads the .gopr.yaml that applies to the current
+//directory, never the global configuration file. A file that has not
+//been allowed is not read, the error wraps project.ErrNotAllowed and the
+//file is returned.
+func findLocalConfig() (*project.LocalConfig, string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := project.FindLocalConfig(wd, globalConfigFiles()...)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkAllowed(f); err != nil {
+		return nil, f, fmt.Errorf("%w, run 'gopr allow' to apply it", err)
+	}
+	local, err := project.ReadLocalConfig(f)
+	return local, f, err
+}
+
+//globalConfigFile returns the path of the gopr configuration file
+func globalConfigFile() string {
+	if f := viper.ConfigFileUsed(); f != "" {
+		return f
+	}
+	return filepath.Join(userHome, ".gopr.yaml")
+}
+
+//globalConfigFiles returns the files that are never taken for a local
+//configuration, the one in use and ~/.gopr.yaml, which are different
+//when --config is given
+func globalConfigFiles() []string {
+	return []string{globalConfigFile(), filepath.Join(userHome, ".gopr.yaml")}
+}
+
+//activeProject returns the name of the project that the current
+//environment was set up for, or an empty string if there is none
+func activeProject() string {
+	if name := os.Getenv(activeEnvVar); name != "" {
+		return name
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" || filepath.Base(gopath) != "go" {
+		return ""
+	}
+	projectpath := filepath.Dir(filepath.Clean(gopath))
+	if filepath.Dir(projectpath) != filepath.Clean(projectsRoot) {
+		return ""
+	}
+	return filepath.Base(projectpath)
+}
+
+//humanSize formats a byte count for display
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func find(slice []string, val string) (int, bool) {
 	for i, item := range slice {
 		if item == val {
@@ -175,22 +524,184 @@ func exitOn(msg string, err error) {
 	}
 }
 
-func (shellCfg *shellConfig) Merge(p *project.Config) {
-	// fmt.Println(p)
-	if p.Go111Module {
-		shellCfg.Go111Module = "on"
+//Merge applies the settings in p. References in the env values are
+//resolved against the other env values, then PROJECT, PROJECT_PATH and
+//GOPATH, then the environment gopr runs in. The path and pathlists
+//directories can refer to the same variables. Lists in pathlists start
+//from the env value of the same name, if any, or the current value.
+//Secrets are masked until ResolveSecrets is called.
+func (shellCfg *shellConfig) Merge(p *project.Config) error {
+	if shellCfg.PathSources == nil {
+		shellCfg.PathSources = map[string]string{}
+	}
+	for _, v := range p.GoEnv() {
+		if v[0] == "GO111MODULE" {
+			shellCfg.Go111Module = v[1]
+		} else {
+			shellCfg.GoEnv = append(shellCfg.GoEnv, envVar{Key: v[0], Value: v[1]})
+		}
 	}
 
 	shellCfg.GoPrivate = p.GoPrivate
-	for k, v := range p.Env {
+
+	if p.GoVersion != "" {
+		shellCfg.GoVersion = toolchain.Normalize(p.GoVersion)
+		shellCfg.GoRoot = toolchain.Dir(toolchainsRoot(), p.GoVersion)
+		shellCfg.Path = pathlist.Edit{Prepend: []string{filepath.Join(shellCfg.GoRoot, "bin")}}.Apply(shellCfg.Path)
+		shellCfg.PathSources[filepath.Join(shellCfg.GoRoot, "bin")] = shellCfg.Sources["GOROOT"]
+	}
+
+	shellCfg.config = p
+	resolve, err := shellCfg.mergeEnv(secret.Redact)
+	if err != nil {
+		return err
+	}
+	edit, err := p.Path.Edit(resolve)
+	if err != nil {
+		return fmt.Errorf("path: %w", err)
+	}
+	shellCfg.Path = edit.Apply(shellCfg.Path)
+	for _, l := range shellCfg.pathLayers {
+		e, err := l.path.Edit(resolve)
+		if err != nil {
+			return fmt.Errorf("path: %w", err)
+		}
+		for _, dirs := range [][]string{e.Prepend, e.Append, e.Remove} {
+			for _, d := range dirs {
+				shellCfg.PathSources[d] = l.file
+			}
+		}
+	}
+	return nil
+}
+
+//ResolveSecrets looks up the secrets the env values refer to and adds
+//the variables of the encrypted secretsFile. Only the commands that set
+//up an environment should call it, everything else shows the masked
+//values from Merge.
+func (shellCfg *shellConfig) ResolveSecrets() error {
+	vars, file, err := readSecretsFile(shellCfg.ProjectPath)
+	if err != nil {
+		return err
+	}
+	if shellCfg.config == nil {
+		shellCfg.config, _ = shellCfg.GetProjectConfig()
+	}
+	for _, v := range vars {
+		shellCfg.config.Env[v.Key] = secret.Scheme + "age/" + v.Key
+		shellCfg.Sources[v.Key] = file
+	}
+	_, err = shellCfg.mergeEnv(secret.Resolve)
+	return err
+}
+
+//mergeEnv sets Env from the env and pathlists of the merged config, with
+//reveal used for the values that refer to secrets. It returns a function
+//that expands references in directories the same way.
+func (shellCfg *shellConfig) mergeEnv(reveal func(string) (string, error)) (func(string) (string, error), error) {
+	p := shellCfg.config
+	x := &project.Expander{
+		Builtins: map[string]string{
+			"PROJECT":      shellCfg.ProjectName,
+			"PROJECT_PATH": shellCfg.ProjectPath,
+			"GOPATH":       shellCfg.GoPath,
+		},
+		Lookup:  os.LookupEnv,
+		Home:    userHome,
+		Resolve: reveal,
+	}
+	env, err := x.Expand(p.Env)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range env {
 		shellCfg.Env[k] = v
 	}
+
+	resolve := func(dir string) (string, error) { return x.ExpandValue(dir, env) }
+	for name, l := range p.PathLists {
+		edit, err := l.Edit(resolve)
+		if err != nil {
+			return nil, fmt.Errorf("pathlists %s: %w", name, err)
+		}
+		list, ok := env[name]
+		if !ok {
+			list, ok = os.LookupEnv(name)
+		}
+		// a list that ends up empty is only set if it was before
+		if v := edit.Apply(list); v != "" || ok {
+			shellCfg.Env[name] = v
+		}
+	}
+	return resolve, nil
+}
+
+//Vars returns the environment variables for the project in the order
+//they should be set, ending with activeEnvVar
+func (shellCfg *shellConfig) Vars() []envVar {
+	return append(shellCfg.ProjectVars(), envVar{Key: activeEnvVar, Value: shellCfg.ProjectName})
+}
+
+//ProjectVars returns the variables of Vars without activeEnvVar, which
+//only means something to gopr itself
+func (shellCfg *shellConfig) ProjectVars() []envVar {
+	vars := []envVar{
+		{Key: "GOPATH", Value: shellCfg.GoPath},
+		{Key: "GO111MODULE", Value: shellCfg.Go111Module},
+		{Key: "GOPRIVATE", Value: shellCfg.GoPrivate},
+	}
+	if shellCfg.GoRoot != "" {
+		vars = append(vars, envVar{Key: "GOROOT", Value: shellCfg.GoRoot})
+	}
+	vars = append(vars, envVar{Key: "PATH", Value: shellCfg.Path})
+	vars = append(vars, shellCfg.GoEnv...)
+
+	keys := make([]string, 0, len(shellCfg.Env))
+	for k := range shellCfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vars = append(vars, envVar{Key: k, Value: shellCfg.Env[k]})
+	}
+	return vars
+}
+
+//Environ returns base, in the format of os.Environ, with the project
+//variables added or replaced
+func (shellCfg *shellConfig) Environ(base []string) []string {
+	vars := shellCfg.Vars()
+	set := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		set[envKey(v.Key)] = true
+	}
+	env := make([]string, 0, len(base)+len(vars))
+	for _, kv := range base {
+		if i := strings.Index(kv, "="); i > 0 && set[envKey(kv[:i])] {
+			continue
+		}
+		env = append(env, kv)
+	}
+	for _, v := range vars {
+		env = append(env, v.Key+"="+v.Value)
+	}
+	return env
+}
+
+//envKey normalizes the name of an environment variable for comparison,
+//names are case insensitive on windows
+func envKey(key string) string {
+	if runtimeOS() == "windows" {
+		return strings.ToUpper(key)
+	}
+	return key
 }
 
 func (shellCfg *shellConfig) GetProjectConfig() (*project.Config, error) {
 	c := &project.Config{
-		Go111Module: shellCfg.Go111Module == "on",
+		Go111Module: shellCfg.Go111Module,
 		GoPrivate:   shellCfg.GoPrivate,
+		GoVersion:   shellCfg.GoVersion,
 		Env:         make(map[string]string),
 	}
 	return c, nil
@@ -203,3 +714,20 @@ func writeProjectConfig(c *project.Config, filename string) error {
 func readProjectConfig(filename string) (*project.Config, error) {
 	return project.ReadConfig(filename)
 }
+
+//upgradeProjectConfig migrates filename to the current schema, telling
+//about it on stderr so it does not end up in generated scripts
+func upgradeProjectConfig(filename string) error {
+	from, err := project.Upgrade(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err == nil && from != project.CurrentVersion {
+		fmt.Fprintf(os.Stderr, "Upgraded %s from version %d to %d, the original is in %s.bak\n",
+			filename, from, project.CurrentVersion, filename)
+		if from < 3 {
+			fmt.Fprintln(os.Stderr, "A ~ at the start of an env value is now your home directory, see 'gopr env --help'")
+		}
+	}
+	return err
+}
@@ -0,0 +1,374 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/project"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	configGlobal  bool
+	configProject string
+
+	// globalSettings are the keys of the global configuration file with
+	// the checks of their values
+	globalSettings = map[string]func(string) error{
+		"root": func(v string) error {
+			if !filepath.IsAbs(v) {
+				return errors.New("must be an absolute path")
+			}
+			return nil
+		},
+		"goprivate": func(v string) error {
+			if strings.ContainsAny(v, " \t") {
+				return errors.New("must be a comma separated list without spaces")
+			}
+			return nil
+		},
+		"go111module": func(v string) error {
+			return project.CheckGoEnv("go111module", v)
+		},
+		"mirror": func(v string) error {
+			if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return errors.New("must be an http or https url")
+			}
+			return nil
+		},
+		identityFileKey: func(v string) error {
+			if !filepath.IsAbs(v) {
+				return errors.New("must be an absolute path")
+			}
+			return nil
+		},
+		"srcdir": func(v string) error {
+			if v == "" {
+				return errors.New("must not be empty")
+			}
+			return nil
+		},
+	}
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Get and set project or global settings",
+	Long: `Get and set the settings in project.yaml or, with --global, in the
+global configuration file ~/.gopr.yaml.
+
+The project is the one given with --project, or the one named by a
+.gopr.yaml in the current directory or one of its parents, or the active
+project.
+
+Project settings are ` + strings.Join(project.ConfigKeys(), ", ") + `.
+Variables are set with env.NAME and can refer to others as ${NAME}. The go
+environment variables are checked like the go command does, go111module is
+on, off or auto. Tools, goprivate, gonosumdb and gonoproxy are comma
+separated lists.
+
+The directories of path.prepend, path.append and path.remove are given as
+a list like PATH. Other lists, like LD_LIBRARY_PATH, are changed the same
+way with pathlists.NAME.prepend and so on.
+
+extends is a comma separated list of projects or profiles, files like
+<root>/profiles/corp.yaml, whose settings the project builds on. Variables
+and pathlists are merged, tools and the path lists are joined and other
+settings are replaced, the project itself first and then later parents over
+earlier ones.
+
+Global settings are ` + strings.Join(globalSettingNames(), ", ") + `.
+
+Values are validated before they are saved and comments in the files are
+kept. edit opens the file in $VISUAL or $EDITOR and validates it after.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+		if configGlobal {
+			exitOn("Invalid setting", checkGlobalKey(key))
+			fmt.Println(viper.GetString(key))
+			return
+		}
+		exitOn("Invalid setting", project.CheckConfigKey(key))
+		d, _ := readConfigDocument()
+		value, ok := d.Get(key)
+		if !ok {
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, value := args[0], args[1]
+		if configGlobal {
+			exitOn("Invalid setting", checkGlobal(key, value))
+			filename := globalConfigFile()
+			d, err := project.ReadDocument(filename)
+			exitOn("Could not read configuration", err)
+			exitOn("Could not set "+key, d.Set(key, project.StringNode(value)))
+			exitOn("Could not write configuration", d.Write(filename))
+			return
+		}
+
+		n, err := project.ConfigValue(key, value)
+		exitOn("Invalid setting", err)
+		d, filename := readConfigDocument()
+		exitOn("Could not set "+key, d.Set(key, n))
+		_, err = d.Config()
+		exitOn("Invalid configuration", err)
+		exitOn("Could not write configuration", d.Write(filename))
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting so the default is used",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+		var d *project.Document
+		var filename string
+		var err error
+		if configGlobal {
+			exitOn("Invalid setting", checkGlobalKey(key))
+			filename = globalConfigFile()
+			d, err = project.ReadDocument(filename)
+			exitOn("Could not read configuration", err)
+		} else {
+			exitOn("Invalid setting", project.CheckConfigKey(key))
+			d, filename = readConfigDocument()
+		}
+		if !d.Unset(key) {
+			er(fmt.Sprintf("%s is not set in %s", key, filename), nil)
+		}
+		exitOn("Could not write configuration", d.Write(filename))
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print all settings",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configGlobal {
+			for _, key := range globalSettingNames() {
+				fmt.Printf("%s=%s\n", key, viper.GetString(key))
+			}
+			return
+		}
+		d, _ := readConfigDocument()
+		for _, key := range d.Keys() {
+			value, _ := d.Get(key)
+			fmt.Printf("%s=%s\n", key, value)
+		}
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the configuration file in an editor",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configGlobal {
+			exitOn("Could not edit configuration", editFile(globalConfigFile(), validateGlobalFile))
+			return
+		}
+		_, filename := readConfigDocument()
+		exitOn("Could not edit configuration", editFile(filename, func(f string) error {
+			_, err := project.ReadConfig(f)
+			return err
+		}))
+	},
+}
+
+//readConfigDocument reads the project.yaml of the project config works on
+func readConfigDocument() (*project.Document, string) {
+	filename := projectConfigPath(targetProject(configProject, "use --project or --global"))
+	exitOn("Could not upgrade configuration", upgradeProjectConfig(filename))
+	d, err := project.ReadConfigDocument(filename)
+	exitOn("Could not read configuration", err)
+	return d, filename
+}
+
+//targetProject returns name or, if it is empty, the project named by a
+//.gopr.yaml in the current directory or one of its parents, or the active
+//project. It exits with hint when there is none, and when the .gopr.yaml
+//can not be used.
+func targetProject(name, hint string) string {
+	if name == "" {
+		local, _, err := findLocalConfig()
+		switch {
+		case err == nil:
+			name = local.Project
+		case err == project.ErrNoLocalConfig:
+			name = activeProject()
+		default:
+			exitOn("Invalid project", err)
+		}
+	}
+	if name == "" {
+		er("No project, "+hint, nil)
+	}
+	found, err := projectExists(name)
+	exitOn("Can not list projects", err)
+	if !found {
+		exitOn("Invalid project", fmt.Errorf("project '%s' not in list", name))
+	}
+	return name
+}
+
+func checkGlobalKey(key string) error {
+	if _, ok := globalSettings[key]; !ok {
+		return fmt.Errorf("%w %q", project.ErrUnknownKey, key)
+	}
+	return nil
+}
+
+func checkGlobal(key, value string) error {
+	if err := checkGlobalKey(key); err != nil {
+		return err
+	}
+	if err := globalSettings[key](value); err != nil {
+		return fmt.Errorf("%w for %s: %v", project.ErrInvalidValue, key, err)
+	}
+	return nil
+}
+
+//validateGlobalFile checks every setting in the global configuration file
+func validateGlobalFile(filename string) error {
+	d, err := project.ReadDocument(filename)
+	if err != nil {
+		return err
+	}
+	for _, key := range d.Keys() {
+		value, _ := d.Get(key)
+		if err := checkGlobal(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//editFile lets the user edit a copy of filename and replaces the file
+//with it when validate accepts the result
+func editFile(filename string, validate func(string) error) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := runEditor(tmp.Name(), validate); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+//runEditor opens filename in $VISUAL or $EDITOR until validate accepts
+//it or the user gives up
+func runEditor(filename string, validate func(string) error) error {
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+	path, err := exec.LookPath(editor[0])
+	if err != nil {
+		return err
+	}
+	for {
+		code, err := runCommand(path, append(editor[1:], filename), os.Environ())
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("%s exited with %d, changes discarded", editor[0], code)
+		}
+		err = validate(filename)
+		if err == nil {
+			return nil
+		}
+		fmt.Println("Invalid content:", err)
+		if !confirm("Edit again?") {
+			return errors.New("changes discarded")
+		}
+	}
+}
+
+func globalSettingNames() []string {
+	names := make([]string, 0, len(globalSettings))
+	for k := range globalSettings {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configEditCmd)
+
+	configCmd.PersistentFlags().BoolVar(&configGlobal, "global", false, "use the global configuration file")
+	configCmd.PersistentFlags().StringVarP(&configProject, "project", "p", "", "the project to configure")
+	// values like -mod=mod for goflags are not flags
+	configSetCmd.Flags().SetInterspersed(false)
+}
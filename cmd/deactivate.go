@@ -0,0 +1,126 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	oldEnvPrefix string = "GOPR_OLD_"
+	oldKeysVar   string = "GOPR_OLD_KEYS"
+)
+
+// deactivateCmd represents the deactivate command
+var deactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Display commands to restore the environment from before env",
+	Long: `Display commands that undo what the env command did to your shell.
+
+When env activates a project it remembers the previous value of every
+variable it changes in GOPR_OLD_* variables. The output of deactivate
+restores those values and unsets the variables that did not exist before.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if os.Getenv(oldKeysVar) == "" {
+			if active := os.Getenv(activeEnvVar); active != "" {
+				er(fmt.Sprintf("Project '%s' was not activated with env, exit the shell to leave it", active), nil)
+			}
+			er("No project is active", nil)
+		}
+
+		cfg, err := shellSyntax()
+		exitOn("Unexpected error", err)
+
+		sets, unsets := deactivation(os.LookupEnv)
+		err = executeTemplateStdout(&envScript{cfg, sets, unsets})
+		exitOn("Unexpected error", err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deactivateCmd)
+
+	deactivateCmd.Flags().StringVar(&userShell, "shell", "", "set custom shell")
+}
+
+//activation returns the variables to set and unset to activate the project,
+//and any extra variables, including what deactivate needs to restore the
+//current values later. If another project is active its saved values are
+//kept and the variables only it changed are restored.
+func (shellCfg *shellConfig) activation(lookup func(string) (string, bool), extra ...envVar) ([]envVar, []string) {
+	vars := append(shellCfg.Vars(), extra...)
+	keys := make([]string, 0, len(vars))
+	changing := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		keys = append(keys, v.Key)
+		changing[v.Key] = true
+	}
+
+	sets := []envVar{}
+	unsets := []string{}
+	saved := make(map[string]bool)
+	for _, k := range oldKeys(lookup) {
+		saved[k] = true
+		if !changing[k] {
+			s, u := restore(k, lookup)
+			sets = append(sets, s...)
+			unsets = append(unsets, u...)
+		}
+	}
+	for _, v := range vars {
+		if saved[v.Key] {
+			continue
+		}
+		if old, ok := lookup(v.Key); ok {
+			sets = append(sets, envVar{Key: oldEnvPrefix + v.Key, Value: old})
+		}
+	}
+	sets = append(sets, envVar{Key: oldKeysVar, Value: strings.Join(keys, ",")})
+	return append(sets, vars...), unsets
+}
+
+//deactivation returns the variables to set and unset to get back to the
+//environment from before the project was activated
+func deactivation(lookup func(string) (string, bool)) ([]envVar, []string) {
+	sets := []envVar{}
+	unsets := []string{}
+	for _, k := range oldKeys(lookup) {
+		s, u := restore(k, lookup)
+		sets = append(sets, s...)
+		unsets = append(unsets, u...)
+	}
+	return sets, append(unsets, oldKeysVar)
+}
+
+func oldKeys(lookup func(string) (string, bool)) []string {
+	keys, _ := lookup(oldKeysVar)
+	if keys == "" {
+		return nil
+	}
+	return strings.Split(keys, ",")
+}
+
+//restore returns how to put back the saved value of key
+func restore(key string, lookup func(string) (string, bool)) ([]envVar, []string) {
+	if old, ok := lookup(oldEnvPrefix + key); ok {
+		return []envVar{{Key: key, Value: old}}, []string{oldEnvPrefix + key}
+	}
+	return nil, []string{key}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+// apply changes env the way evaluating the generated script would
+func apply(env map[string]string, script func(func(string) (string, bool)) ([]envVar, []string)) {
+	sets, unsets := script(lookupIn(env))
+	for _, k := range unsets {
+		delete(env, k)
+	}
+	for _, v := range sets {
+		env[v.Key] = v.Value
+	}
+}
+
+// activate returns cfg.activation without extra variables
+func activate(cfg *shellConfig) func(func(string) (string, bool)) ([]envVar, []string) {
+	return func(lookup func(string) (string, bool)) ([]envVar, []string) {
+		return cfg.activation(lookup)
+	}
+}
+
+func testShellConfig(name string, env map[string]string) *shellConfig {
+	return &shellConfig{
+		ProjectName: name,
+		GoPath:      "/gopr/" + name + "/go",
+		Go111Module: "on",
+		Path:        "/gopr/" + name + "/go/bin:/usr/bin",
+		Env:         env,
+	}
+}
+
+func TestActivationRoundTrip(t *testing.T) {
+	env := map[string]string{
+		"GOPATH":    "/home/user/go",
+		"PATH":      "/usr/bin",
+		"GOPRIVATE": "",
+		"HOME":      "/home/user",
+	}
+	before := map[string]string{}
+	for k, v := range env {
+		before[k] = v
+	}
+
+	cfg := testShellConfig("foo", map[string]string{"DOCKER_HOST": "ssh://foo"})
+	apply(env, activate(cfg))
+	assert.Equal(t, "/gopr/foo/go", env["GOPATH"])
+	assert.Equal(t, "ssh://foo", env["DOCKER_HOST"])
+	assert.Equal(t, "foo", env[activeEnvVar])
+	assert.Equal(t, "/home/user/go", env["GOPR_OLD_GOPATH"])
+
+	apply(env, deactivation)
+	assert.Equal(t, before, env)
+}
+
+func TestActivationSwitchProject(t *testing.T) {
+	env := map[string]string{
+		"GOPATH": "/home/user/go",
+		"PATH":   "/usr/bin",
+		"EDITOR": "vi",
+	}
+	before := map[string]string{}
+	for k, v := range env {
+		before[k] = v
+	}
+
+	foo := testShellConfig("foo", map[string]string{"DOCKER_HOST": "ssh://foo", "EDITOR": "code"})
+	apply(env, activate(foo))
+	bar := testShellConfig("bar", map[string]string{"AWS_PROFILE": "bar"})
+	apply(env, activate(bar))
+
+	assert.Equal(t, "/gopr/bar/go", env["GOPATH"])
+	assert.Equal(t, "vi", env["EDITOR"])
+	_, found := env["DOCKER_HOST"]
+	assert.False(t, found)
+
+	apply(env, deactivation)
+	assert.Equal(t, before, env)
+}
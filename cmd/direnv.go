@@ -0,0 +1,154 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/spf13/cobra"
+)
+
+const (
+	envrcFile = ".envrc"
+	//envrcTmpl is written by direnv init, it does not depend on where gopr
+	//is installed so it can be committed
+	envrcTmpl = `# Load the environment of the gopr project, see 'gopr direnv --help'
+type use_gopr >/dev/null 2>&1 || eval "$(gopr direnv stdlib)"
+use gopr {{ .Project }}
+`
+	//direnvStdlibTmpl defines use_gopr for direnv
+	direnvStdlibTmpl = `# use gopr [project]
+#
+# Loads the environment of a gopr project. Without a project name it is
+# found from the .gopr.yaml in the current directory or one of its parents.
+use_gopr() {
+  eval "$({{ .Gopr }} direnv export "$@")"
+}
+`
+)
+
+var direnvForce bool
+
+// direnvCmd represents the direnv command
+var direnvCmd = &cobra.Command{
+	Use:   "direnv",
+	Short: "Integrate with direnv",
+	Long: `Integrate gopr projects with direnv.
+
+'gopr direnv init <project>' writes an .envrc that loads the project with
+'use gopr <project>'. The use_gopr function is defined by the output of
+'gopr direnv stdlib', which the .envrc evaluates unless you already did
+in ~/.config/direnv/direnvrc. The project.yaml, the projects and profiles
+it extends, the .gopr.yaml and project.env.age are watched so that direnv
+reloads the environment when one of them changes.`,
+}
+
+var direnvInitCmd = &cobra.Command{
+	Use:   "init <project>",
+	Short: "Write an .envrc that loads the project",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		projectName := args[0]
+		found, err := projectExists(projectName)
+		exitOn("Can not list projects", err)
+		if !found {
+			exitOn("Invalid project", fmt.Errorf("project '%s' not in list", projectName))
+		}
+
+		if _, err := os.Stat(envrcFile); err == nil && !direnvForce {
+			er(fmt.Sprintf("%s exists, use --force to overwrite it", envrcFile), nil)
+		}
+
+		envrc, err := renderEnvrc(projectName)
+		exitOn("Unexpected error", err)
+		err = ioutil.WriteFile(envrcFile, []byte(envrc), 0644)
+		exitOn("Could not write "+envrcFile, err)
+		fmt.Printf("Wrote %s, run 'direnv allow' to load it\n", envrcFile)
+	},
+}
+
+var direnvStdlibCmd = &cobra.Command{
+	Use:   "stdlib",
+	Short: "Display the use_gopr function for direnv",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exe, err := os.Executable()
+		exitOn("Can not find gopr", err)
+
+		tmpl := template.Must(template.New("stdlib").Parse(direnvStdlibTmpl))
+		err = tmpl.Execute(os.Stdout, map[string]string{"Gopr": quote("bash", exe)})
+		exitOn("Unexpected error", err)
+	},
+}
+
+var direnvExportCmd = &cobra.Command{
+	Use:   "export [project]",
+	Short: "Display the project environment for use_gopr",
+	Long: `Display the project environment as bash code for the use_gopr function.
+direnv keeps track of what changes itself, so unlike env nothing is saved
+for deactivate.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		userShell = "bash"
+		cfg, err := projectFromArgs(args)
+		exitOn("Invalid project", err)
+		exitOn("Unexpected error", cfg.useShell())
+		exitOn("Could not resolve secrets", cfg.ResolveSecrets())
+		markActivated(cfg)
+
+		sh, _ := shell.Get("bash")
+		for _, f := range direnvWatches(cfg) {
+			fmt.Println("watch_file", sh.Quote(f))
+		}
+		cfg.UsageHint = ""
+		err = executeTemplateStdout(&envScript{cfg, cfg.Vars(), nil})
+		exitOn("Unexpected error", err)
+	},
+}
+
+//renderEnvrc returns the envrcFile that loads projectName
+func renderEnvrc(projectName string) (string, error) {
+	var b strings.Builder
+	tmpl := template.Must(template.New("envrc").Parse(envrcTmpl))
+	err := tmpl.Execute(&b, map[string]string{"Project": quote("bash", projectName)})
+	return b.String(), err
+}
+
+//direnvWatches returns the files direnv should reload the environment
+//for, every configuration file of cfg and the secretsFile, whether they
+//exist yet or not
+func direnvWatches(cfg *shellConfig) []string {
+	watch := append([]string{}, cfg.Files...)
+	if _, found := find(watch, cfg.ConfigFile); !found {
+		watch = append(watch, cfg.ConfigFile)
+	}
+	return append(watch, filepath.Join(cfg.ProjectPath, secretsFile))
+}
+
+func init() {
+	rootCmd.AddCommand(direnvCmd)
+	direnvCmd.AddCommand(direnvInitCmd)
+	direnvCmd.AddCommand(direnvStdlibCmd)
+	direnvCmd.AddCommand(direnvExportCmd)
+
+	direnvInitCmd.Flags().BoolVarP(&direnvForce, "force", "f", false, "overwrite an existing "+envrcFile)
+}
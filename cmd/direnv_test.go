@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEnvrc(t *testing.T) {
+	envrc, err := renderEnvrc("my project")
+	assert.NoError(t, err)
+	assert.Contains(t, envrc, "use gopr 'my project'\n")
+	assert.Contains(t, envrc, `eval "$(gopr direnv stdlib)"`)
+}
+
+func TestDirenvWatches(t *testing.T) {
+	project := filepath.Join("gopr", "foo")
+	cfg := &shellConfig{
+		ProjectPath: project,
+		ConfigFile:  filepath.Join(project, projectConfigFile),
+	}
+	assert.Equal(t, []string{cfg.ConfigFile, filepath.Join(project, secretsFile)}, direnvWatches(cfg))
+
+	base := filepath.Join("gopr", "profiles", "base.yaml")
+	local := filepath.Join("src", "foo", ".gopr.yaml")
+	cfg.Files = []string{base, cfg.ConfigFile, local}
+	assert.Equal(t, []string{base, cfg.ConfigFile, local, filepath.Join(project, secretsFile)}, direnvWatches(cfg))
+}
@@ -0,0 +1,232 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/kmpm/gopr/lib/toolchain"
+	"github.com/spf13/cobra"
+)
+
+//finding is the outcome of a doctor check. Fix tells how to solve a
+//problem and is empty for passed checks.
+type finding struct {
+	Problem bool
+	Message string
+	Fix     string
+}
+
+func passed(format string, a ...interface{}) finding {
+	return finding{Message: fmt.Sprintf(format, a...)}
+}
+
+func problem(fix, format string, a ...interface{}) finding {
+	return finding{Problem: true, Message: fmt.Sprintf(format, a...), Fix: fix}
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check projects and the host setup for problems",
+	Long: `Check projects and the host setup for problems and tell how to fix them.
+
+The checks are
+  - the projects root exists and is writable
+  - every project directory has a go directory, otherwise it is not listed
+  - every project.yaml exists and can be read
+  - pinned go versions are installed
+  - PATH has no bin directories of projects that are not active
+  - GOPATH is not inherited from outside the active project
+  - the shell is one gopr can generate code for
+  - a go binary is found and matches the version the active project pins
+
+The exit status is 1 if any problem was found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		active := activeProject()
+		var findings []finding
+		findings = append(findings, checkRoot(projectsRoot))
+		findings = append(findings, checkProjects(projectsRoot)...)
+		findings = append(findings, checkPath(os.Getenv("PATH"), projectsRoot, active)...)
+		findings = append(findings, checkGoPath(os.Getenv("GOPATH"), active))
+		findings = append(findings, checkShell(userShell))
+		findings = append(findings, checkGo(active))
+
+		problems := 0
+		for _, f := range findings {
+			if !f.Problem {
+				fmt.Println("ok     ", f.Message)
+				continue
+			}
+			problems++
+			fmt.Println("PROBLEM", f.Message)
+			if f.Fix != "" {
+				fmt.Println("        fix:", f.Fix)
+			}
+		}
+		if problems > 0 {
+			fmt.Printf("\n%d problem(s) found\n", problems)
+			os.Exit(1)
+		}
+		fmt.Println("\nNo problems found")
+	},
+}
+
+//checkRoot makes sure projects can be created in root
+func checkRoot(root string) finding {
+	info, err := os.Stat(root)
+	if err != nil {
+		return problem(fmt.Sprintf("create it with 'mkdir -p %s' or set --root", root),
+			"projects root %s does not exist", root)
+	}
+	if !info.IsDir() {
+		return problem("remove it or set --root to a directory", "projects root %s is not a directory", root)
+	}
+	f, err := ioutil.TempFile(root, ".doctor-")
+	if err != nil {
+		return problem(fmt.Sprintf("make %s writable for your user", root), "projects root %s is not writable", root)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return passed("projects root %s is writable", root)
+}
+
+//checkProjects looks at every directory in root that is not used by gopr
+//itself
+func checkProjects(root string) []finding {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var findings []finding
+	for _, e := range entries {
+		name := e.Name()
+		if _, reserved := find(reservedNames, name); reserved || !e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		dir := filepath.Join(root, name)
+		if info, err := os.Stat(filepath.Join(dir, "go")); err != nil || !info.IsDir() {
+			findings = append(findings, problem(fmt.Sprintf("run 'mkdir %s' or remove the directory", filepath.Join(dir, "go")),
+				"project '%s' has no go directory and is not listed", name))
+			continue
+		}
+
+		pc, err := readMergedConfig(name)
+		switch {
+		case os.IsNotExist(err):
+			findings = append(findings, problem("add settings to it or copy one from another project",
+				"project '%s' has no %s, defaults are used", name, projectConfigFile))
+		case err != nil:
+			findings = append(findings, problem("correct the file", "project '%s': %v", name, err))
+		case pc.GoVersion != "" && !toolchain.Installed(toolchainsRoot(), pc.GoVersion):
+			findings = append(findings, problem(fmt.Sprintf("run 'gopr toolchain install %s'", pc.GoVersion),
+				"project '%s' pins %s which is not installed", name, pc.GoVersion))
+		default:
+			findings = append(findings, passed("project '%s'", name))
+		}
+	}
+	return findings
+}
+
+//checkPath finds bin directories of other projects than active in path
+func checkPath(path, root, active string) []finding {
+	var findings []finding
+	for _, p := range filepath.SplitList(path) {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) == 3 && parts[1] == "go" && parts[2] == "bin" && parts[0] != active {
+			findings = append(findings, problem("start a new shell or run 'gopr deactivate'",
+				"PATH contains %s of project '%s' which is not active", p, parts[0]))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, passed("PATH has no stale project directories"))
+	}
+	return findings
+}
+
+//checkGoPath makes sure GOPATH belongs to the active project, if any
+func checkGoPath(gopath, active string) finding {
+	if active == "" {
+		if gopath != "" {
+			return problem("unset GOPATH in your shell profile and let gopr set it",
+				"GOPATH is set to %s without an active project and leaks into new projects", gopath)
+		}
+		return passed("GOPATH is not set")
+	}
+	want := filepath.Join(projectsRoot, active, "go")
+	if filepath.Clean(gopath) != want {
+		return problem(fmt.Sprintf("run 'gopr env %s' again", active),
+			"GOPATH is %s but the active project '%s' uses %s", gopath, active, want)
+	}
+	return passed("GOPATH is set by project '%s'", active)
+}
+
+//checkShell makes sure the shell has a syntax, env falls back to sh
+func checkShell(userShell string) finding {
+	name, err := getShell(userShell)
+	if err != nil {
+		return problem("use --shell to name it", "could not detect the shell: %v", err)
+	}
+	if _, err := shell.Get(name); err != nil {
+		return problem("use --shell with one of "+strings.Join(shell.Names(), ", "),
+			"unknown shell '%s', sh syntax is used", name)
+	}
+	return passed("shell %s is supported", name)
+}
+
+//checkGo makes sure there is a go binary and that it is the version the
+//active project pins
+func checkGo(active string) finding {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		return problem("install go or pin a version with goversion and 'gopr toolchain install'",
+			"no go binary in PATH")
+	}
+	// go version prints like "go version go1.14.1 linux/amd64"
+	out, err := exec.Command(goCmd, "version").Output()
+	fields := strings.Fields(string(out))
+	if err != nil || len(fields) < 3 {
+		return problem("reinstall go", "could not get the version of %s: %v", goCmd, err)
+	}
+	version := fields[2]
+	if active != "" {
+		if pc, err := readMergedConfig(active); err == nil && pc.GoVersion != "" {
+			if want := toolchain.Normalize(pc.GoVersion); want != version {
+				return problem(fmt.Sprintf("run 'gopr env %s' again", active),
+					"%s is %s but project '%s' pins %s", goCmd, version, active, want)
+			}
+		}
+	}
+	return passed("%s is %s", goCmd, version)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&userShell, "shell", "", "the shell to check")
+}
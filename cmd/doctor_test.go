@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPath(t *testing.T) {
+	root := filepath.Join(os.TempDir(), "gopr")
+	path := strings.Join([]string{
+		filepath.Join(root, "a", "go", "bin"),
+		filepath.Join(root, "b", "go", "bin"),
+		filepath.Join(root, "toolchains", "go1.14", "bin"),
+		"/usr/bin",
+	}, string(os.PathListSeparator))
+
+	findings := checkPath(path, root, "a")
+	if assert.Len(t, findings, 1) {
+		assert.True(t, findings[0].Problem)
+		assert.Contains(t, findings[0].Message, "project 'b'")
+	}
+
+	findings = checkPath("/usr/bin", root, "")
+	if assert.Len(t, findings, 1) {
+		assert.False(t, findings[0].Problem)
+	}
+}
+
+func TestCheckShell(t *testing.T) {
+	assert.False(t, checkShell("bash").Problem)
+	assert.True(t, checkShell("cshell").Problem)
+}
+
+func TestCheckRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopr")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	readOnly := filepath.Join(dir, "readonly")
+	assert.NoError(t, os.Mkdir(readOnly, 0555))
+
+	for _, tc := range []struct {
+		root    string
+		problem bool
+		message string
+	}{
+		{dir, false, "is writable"},
+		{filepath.Join(dir, "missing"), true, "does not exist"},
+		{file, true, "is not a directory"},
+		{readOnly, true, "is not writable"},
+	} {
+		if tc.root == readOnly && os.Geteuid() == 0 {
+			// root can write anyway
+			continue
+		}
+		f := checkRoot(tc.root)
+		assert.Equal(t, tc.problem, f.Problem, tc.root)
+		assert.Contains(t, f.Message, tc.message, tc.root)
+	}
+}
+
+func TestCheckProjects(t *testing.T) {
+	cleanup := testProjects(t, map[string]string{
+		"good":    "version: 3\n",
+		"bare":    "",
+		"broken":  "version: 3\ngoprivate: [\n",
+		"pinned":  "version: 3\ngoversion: go1.14.99\n",
+		"nogo":    "",
+		"extends": "version: 3\nextends: [missing]\n",
+	}, nil)
+	defer cleanup()
+	assert.NoError(t, os.RemoveAll(filepath.Join(projectsRoot, "nogo", "go")))
+	assert.NoError(t, os.MkdirAll(filepath.Join(projectsRoot, ".hidden"), 0755))
+
+	findings := map[string]finding{}
+	for _, f := range checkProjects(projectsRoot) {
+		name := strings.SplitN(f.Message, "'", 3)[1]
+		findings[name] = f
+	}
+	for _, tc := range []struct {
+		name    string
+		problem bool
+		message string
+	}{
+		{"good", false, "project 'good'"},
+		{"bare", true, "has no project.yaml"},
+		{"broken", true, "project.yaml"},
+		{"pinned", true, "pins go1.14.99 which is not installed"},
+		{"nogo", true, "has no go directory"},
+		{"extends", true, "missing"},
+	} {
+		f, ok := findings[tc.name]
+		if assert.True(t, ok, tc.name) {
+			assert.Equal(t, tc.problem, f.Problem, tc.name)
+			assert.Contains(t, f.Message, tc.message, tc.name)
+		}
+	}
+	assert.Len(t, findings, 6)
+}
+
+func TestCheckGoPath(t *testing.T) {
+	oldRoot := projectsRoot
+	projectsRoot = filepath.Join(os.TempDir(), "gopr")
+	defer func() { projectsRoot = oldRoot }()
+
+	for _, tc := range []struct {
+		gopath  string
+		active  string
+		problem bool
+		message string
+	}{
+		{"", "", false, "not set"},
+		{"/home/user/go", "", true, "without an active project"},
+		{filepath.Join(projectsRoot, "a", "go"), "a", false, "set by project 'a'"},
+		{filepath.Join(projectsRoot, "a", "go") + string(os.PathSeparator), "a", false, "set by project 'a'"},
+		{filepath.Join(projectsRoot, "b", "go"), "a", true, "the active project 'a'"},
+		{"", "a", true, "the active project 'a'"},
+	} {
+		f := checkGoPath(tc.gopath, tc.active)
+		assert.Equal(t, tc.problem, f.Problem, tc.gopath)
+		assert.Contains(t, f.Message, tc.message, tc.gopath)
+	}
+}
@@ -18,57 +18,92 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"text/template"
 
+	"github.com/kmpm/gopr/lib/envfile"
+	"github.com/kmpm/gopr/lib/secret"
+	"github.com/kmpm/gopr/lib/shell"
 	"github.com/spf13/cobra"
 )
 
 const (
-	//envTmpl = `{{ .Prefix }}DOCKER_TLS_VERIFY{{ .Delimiter }}{{ .DockerTLSVerify }}{{ .Suffix }}{{ .Prefix }}DOCKER_HOST{{ .Delimiter }}{{ .DockerHost }}{{ .Suffix }}{{ .Prefix }}DOCKER_CERT_PATH{{ .Delimiter }}{{ .DockerCertPath }}{{ .Suffix }}{{ .Prefix }}DOCKER_MACHINE_NAME{{ .Delimiter }}{{ .MachineName }}{{ .Suffix }}{{ if .ComposePathsVar }}{{ .Prefix }}COMPOSE_CONVERT_WINDOWS_PATHS{{ .Delimiter }}true{{ .Suffix }}{{end}}{{ if .NoProxyVar }}{{ .Prefix }}{{ .NoProxyVar }}{{ .Delimiter }}{{ .NoProxyValue }}{{ .Suffix }}{{end}}{{ .UsageHint }}`
 	//envTmpl contains the template to show
-	envTmpl = `{{ .Prefix }}GOPATH{{ .Delimiter }}{{ .GoPath }}{{ .Suffix }}{{ .Prefix }}GO111MODULE{{ .Delimiter }}{{ .Go111Module }}{{ .Suffix }}{{ .Prefix }}GOPRIVATE{{ .Delimiter }}{{ .GoPrivate }}{{ .Suffix }}{{.Prefix}}PATH{{.Delimiter}}{{.Path}}{{.Suffix}}{{.Comment}}
-{{ range $key, $value := .Env }}{{$.Prefix}}{{$key}}{{$.Delimiter}}{{$value}}{{$.Suffix}}{{end}}{{.Comment}}
-{{ .UsageHint }}`
+	envTmpl = `{{ range .Unsets }}{{ $.Shell.Unset . }}
+{{ end }}{{ range .Sets }}{{ $.Shell.Set .Key .Value }}
+{{ end }}{{ .UsageHint }}`
 )
 
 var (
 	userShell          string
+	envFormat          string
+	envAuto            bool
 	defaultUsageHinter UsageHintGenerator
 )
 
 // envCmd represents the env command
 var envCmd = &cobra.Command{
-	Use:   "env",
+	Use:   "env [project]",
 	Short: "Display commands to set up environment for the go project",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Long: `Display the commands that set up your shell for the go project.
+
+Without a project name the project is found from a .gopr.yaml in the
+current directory or one of its parents. Settings in that file take
+precedence over the project.yaml of the project. The file is only used
+once you allowed it, see 'gopr allow --help'.
+
+Values in env can refer to other variables as ${NAME} or $NAME. A name is
+looked up among the env values of the project first, then in the ones gopr
+provides, PROJECT, PROJECT_PATH and GOPATH, and last in the current
+environment. A value that refers to its own name, like PATH, gets the
+value from the current environment. Use $$ for a literal $. A ~ at the
+start of a value is your home directory.
+
+Values like secret://provider/name are looked up when the environment is
+set up and are never saved by gopr. The providers are
+
+  file     secret://file/<path>, the content of an absolute or ~/ path
+  cmd      secret://cmd/<command>, what the command writes to stdout
+  pass     secret://pass/<entry>, the first line of a pass entry
+  keyring  secret://keyring/<name>, account name of service gopr in the
+           keyring, with secret-tool on linux and security on macOS
+
+References are used as they are, variables in them are not expanded. The
+cmd, pass and keyring providers run programs and file reads any file you
+can, so a .gopr.yaml with references is only looked up once you allowed
+it, like any other setting in it. info and other commands only show ` + secret.Mask + ` for them.
+
+With --format the resolved environment is written in a machine readable
+format instead of shell code, without the GOPR_ACTIVE variable gopr uses
+to tell which project is active:
+
+  json             a single JSON object
+  dotenv           a .env file
+  systemd          a file for EnvironmentFile= in systemd units
+  docker-env-file  a file for docker run --env-file
+  github-actions   lines to append to $GITHUB_ENV`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) != 1 {
-			exitOn("parameter error", ErrInvalidProjectName)
+		if envAuto {
+			autoEnv()
+			return
 		}
-		projectName := args[0]
 
-		found, err := projectExists(projectName)
-		exitOn("Can not list projects", err)
+		cfg, err := projectFromArgs(args)
+		exitOn("Invalid project", err)
+		exitOn("Could not resolve secrets", cfg.ResolveSecrets())
+		markActivated(cfg)
 
-		if !found {
-			exitOn("Invalid project", fmt.Errorf("project not in list"))
+		if envFormat != "shell" {
+			err = envfile.Write(os.Stdout, envFormat, cfg.ProjectVars())
+			exitOn("Unexpected error", err)
+			return
 		}
 
-		cfg, err := shellCfgSet(projectName)
-		exitOn("Unexpected error", err)
-
-		pc, err := readProjectConfig(cfg.ConfigFile)
-		if err == nil {
-			cfg.Merge(pc)
-		}
+		exitOn("Could not detect shell", cfg.useShell())
 
-		err = executeTemplateStdout(cfg)
+		sets, unsets := cfg.activation(os.LookupEnv)
+		err = executeTemplateStdout(&envScript{cfg, sets, unsets})
 		exitOn("Unexpected error", err)
 
 	},
@@ -87,15 +122,52 @@ func init() {
 	// is called directly, e.g.:
 	// envCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	envCmd.Flags().StringVar(&userShell, "shell", "", "set custom shell")
+	envCmd.Flags().BoolVar(&envAuto, "auto", false, "activate or deactivate for the allowed .gopr.yaml of the current directory, used by hook")
+	envCmd.Flags().StringVar(&envFormat, "format", "shell", "output format, one of shell, "+strings.Join(formatNames(), ", "))
 }
 
-func executeTemplateStdout(shellCfg *shellConfig) error {
+func formatNames() []string {
+	names := make([]string, 0, len(envfile.Formats))
+	for name := range envfile.Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//envScript is what envTmpl renders, the variables to change using the
+//syntax of the embedded shellConfig
+type envScript struct {
+	*shellConfig
+	Sets   []envVar
+	Unsets []string
+}
+
+//validate makes sure that the shell can represent every name and value
+func (script *envScript) validate() error {
+	for _, v := range script.Sets {
+		if err := shell.Validate(script.Shell, v.Key, v.Value); err != nil {
+			return err
+		}
+	}
+	for _, k := range script.Unsets {
+		if !shell.ValidName(k) {
+			return fmt.Errorf("%w: %q", shell.ErrInvalidName, k)
+		}
+	}
+	return nil
+}
+
+func executeTemplateStdout(script *envScript) error {
+	if err := script.validate(); err != nil {
+		return err
+	}
 	t := template.New("envConfig")
 	tmpl, err := t.Parse(envTmpl)
 	if err != nil {
 		return err
 	}
-	return tmpl.Execute(os.Stdout, shellCfg)
+	return tmpl.Execute(os.Stdout, script)
 }
 
 type UsageHintGenerator interface {
@@ -105,8 +177,10 @@ type UsageHintGenerator interface {
 type EnvUsageHintGenerator struct{}
 
 func (g *EnvUsageHintGenerator) GenerateUsageHint(userShell string, args []string) string {
-	cmd := ""
-	comment := "#"
+	sh, err := shell.Get(userShell)
+	if err != nil {
+		sh, _ = shell.Get("sh")
+	}
 
 	projectPath := args[0]
 	if strings.Contains(projectPath, " ") || strings.Contains(projectPath, `\`) {
@@ -115,23 +189,5 @@ func (g *EnvUsageHintGenerator) GenerateUsageHint(userShell string, args []strin
 
 	commandLine := strings.Join(args, " ")
 
-	switch userShell {
-	case "fish":
-		cmd = fmt.Sprintf("eval (%s)", commandLine)
-	case "powershell":
-		cmd = fmt.Sprintf("& %s | Invoke-Expression", commandLine)
-	case "cmd":
-		cmd = fmt.Sprintf("\t@FOR /f \"tokens=*\" %%i IN ('%s') DO @%%i", commandLine)
-		comment = "REM"
-	case "emacs":
-		cmd = fmt.Sprintf("(with-temp-buffer (shell-command \"%s\" (current-buffer)) (eval-buffer))", commandLine)
-		comment = ";;"
-	case "tcsh":
-		cmd = fmt.Sprintf("eval `%s`", commandLine)
-		comment = ":"
-	default:
-		cmd = fmt.Sprintf("eval $(%s)", commandLine)
-	}
-
-	return fmt.Sprintf("%s Run this command to configure your shell: \n%s %s\n", comment, comment, cmd)
+	return fmt.Sprintf("%s\n%s\n", sh.Comment("Run this command to configure your shell:"), sh.Comment(sh.Eval(commandLine)))
 }
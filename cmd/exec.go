@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec <project> -- <command> [args...]",
+	Short: "Run a command in the go project environment",
+	Long: `Run a single command with the environment of the project, the same
+environment the env command prints, without starting a shell.
+
+The command is looked up in the PATH of the project. Signals are passed on
+to the command and gopr exits with the exit code of the command, which
+makes it suitable for scripts and Makefiles.
+
+    gopr exec myproject -- go build ./...`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			exitOn("parameter error", fmt.Errorf("you must provide a project name and a command"))
+		}
+		projectName := args[0]
+		command := args[1:]
+		// with interspersed flags disabled the separator is passed on as an argument
+		if command[0] == "--" {
+			command = command[1:]
+		}
+		if len(command) == 0 {
+			exitOn("parameter error", fmt.Errorf("you must provide a command"))
+		}
+
+		cfg, err := loadProject(projectName)
+		exitOn("Invalid project", err)
+		exitOn("Could not resolve secrets", cfg.ResolveSecrets())
+
+		path, err := lookPath(command[0], cfg.Path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gopr:", err)
+			os.Exit(127)
+		}
+
+		markActivated(cfg)
+		code, err := runCommand(path, command[1:], cfg.Environ(os.Environ()))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gopr:", err)
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// everything after the project name belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/kmpm/gopr/lib/project"
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/spf13/cobra"
+)
+
+const (
+	autoEnvVar string = "GOPR_AUTO"
+)
+
+// hookTmpls contains the hook for each supported shell
+var hookTmpls = map[string]string{
+	"bash": `_gopr_hook() {
+  local previous_exit_status=$?
+  eval "$({{ .Gopr }} env --auto --shell bash)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_gopr_hook;"* ]]; then
+  PROMPT_COMMAND="_gopr_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	"zsh": `_gopr_hook() {
+  eval "$({{ .Gopr }} env --auto --shell zsh)"
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_gopr_hook]} )); then
+  chpwd_functions=(_gopr_hook $chpwd_functions)
+fi
+_gopr_hook
+`,
+	"fish": `function __gopr_hook --on-variable PWD --description 'gopr auto activation'
+    {{ .Gopr }} env --auto --shell fish | source
+end
+__gopr_hook
+`,
+}
+
+// hookCmd represents the hook command
+var hookCmd = &cobra.Command{
+	Use:   "hook <shell>",
+	Short: "Display the shell code that activates projects on cd",
+	Long: `Display shell code that activates the project configured in a .gopr.yaml
+whenever you enter its directory, and deactivates it again when you leave.
+Projects activated by hand with env are left alone.
+
+A .gopr.yaml is only applied once you trusted it with 'gopr allow', and
+again after every change to it. Files that are not allowed are passed over
+with a warning, see 'gopr allow --help'.
+
+Supported shells are bash, zsh and fish. Add one of these to the end of
+your shell configuration:
+
+  bash (~/.bashrc)                eval "$(gopr hook bash)"
+  zsh (~/.zshrc)                  eval "$(gopr hook zsh)"
+  fish (~/.config/fish/config.fish)  gopr hook fish | source`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tmplText, ok := hookTmpls[args[0]]
+		if !ok {
+			exitOn("Can not create hook", fmt.Errorf("%w: %s", shell.ErrUnknownShell, args[0]))
+		}
+		exe, err := os.Executable()
+		exitOn("Can not find gopr", err)
+		sh, _ := shell.Get(args[0])
+
+		tmpl := template.Must(template.New("hook").Parse(tmplText))
+		err = tmpl.Execute(os.Stdout, map[string]string{"Gopr": sh.Quote(exe)})
+		exitOn("Unexpected error", err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+}
+
+//checkAllowed returns project.ErrNotAllowed unless the local
+//configuration file has been allowed with its current content
+func checkAllowed(file string) error {
+	list, err := project.ReadAllowList(allowListFile())
+	if err != nil {
+		return err
+	}
+	return list.Check(file)
+}
+
+//autoEnv prints what is needed to switch to the project configured for
+//the current directory, or to leave an automatically activated project.
+//Nothing is printed when there is nothing to change. Since the output is
+//evaluated by the hook, errors go to stderr only.
+func autoEnv() {
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "gopr:", err)
+		os.Exit(1)
+	}
+	auto := os.Getenv(autoEnvVar)
+
+	local, file, err := findLocalConfig()
+	if errors.Is(err, project.ErrNotAllowed) {
+		// as if there was no file, an automatically activated project is left
+		fmt.Fprintln(os.Stderr, "gopr:", err)
+	} else if err != nil && err != project.ErrNoLocalConfig {
+		fail(err)
+	}
+
+	var script *envScript
+	switch {
+	case local != nil:
+		dir := filepath.Dir(file)
+		if auto == dir || (auto == "" && activeProject() != "") {
+			// already active, or activated by hand
+			return
+		}
+		cfg, err := loadProject(local.Project, local)
+		if err == nil {
+			err = cfg.useShell()
+		}
+		if err == nil {
+			err = cfg.ResolveSecrets()
+		}
+		if err != nil {
+			fail(err)
+		}
+		markActivated(cfg)
+		sets, unsets := cfg.activation(os.LookupEnv, envVar{Key: autoEnvVar, Value: dir})
+		script = &envScript{cfg, sets, unsets}
+	case auto != "":
+		cfg, err := shellSyntax()
+		if err != nil {
+			fail(err)
+		}
+		sets, unsets := deactivation(os.LookupEnv)
+		script = &envScript{cfg, sets, unsets}
+	default:
+		return
+	}
+
+	script.UsageHint = ""
+	if err := executeTemplateStdout(script); err != nil {
+		fail(err)
+	}
+}
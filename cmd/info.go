@@ -0,0 +1,188 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kmpm/gopr/lib/pathlist"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var infoJSON bool
+
+//setting is a resolved value and where it came from
+type setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+//projectInfo is the resolved configuration of a project
+type projectInfo struct {
+	Project    string    `json:"project"`
+	Path       string    `json:"path"`
+	ConfigFile string    `json:"config_file"`
+	Extends    []string  `json:"extends"`
+	Settings   []setting `json:"settings"`
+	Vars       []setting `json:"vars"`
+	PathAdded  []string  `json:"path_added"`
+	PathRemove []string  `json:"path_removed"`
+	// PathSources tells which file, or gopr, added or removed each
+	// directory in PathAdded and PathRemove
+	PathSources map[string]string `json:"path_sources"`
+	// SecretsFile is set if the project has encrypted variables
+	SecretsFile string `json:"secrets_file,omitempty"`
+}
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info [project]",
+	Short: "Show the resolved configuration of a project",
+	Long: `Show every setting and variable env would use for a project together
+with where the value came from. The sources are, from lowest to highest
+precedence
+
+  default          built into gopr
+  config <file>    the global configuration file, ~/.gopr.yaml
+  env GOPR_<KEY>   an environment variable like GOPR_ROOT or GOPR_GOPRIVATE,
+                   ROOT, GOPRIVATE and GO111MODULE are read without the
+                   prefix when the GOPR_ one is not set
+  flag --<key>     a command line flag
+  <project.yaml>   of a project or a profile the project extends
+  <project.yaml>   the project configuration
+  <.gopr.yaml>     the local configuration found from the current directory
+
+The encrypted variables of project.env.age are not shown, see
+'gopr secrets --help'.
+
+Without a project name the project is found from a .gopr.yaml in the
+current directory or one of its parents.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := projectFromArgs(args)
+		exitOn("Invalid project", err)
+		info := getProjectInfo(cfg)
+
+		if infoJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			exitOn("Unexpected error", enc.Encode(info))
+			return
+		}
+
+		fmt.Printf("Project  %s\n", info.Project)
+		fmt.Printf("Path     %s\n", info.Path)
+		fmt.Printf("Config   %s\n", info.ConfigFile)
+		if len(info.Extends) > 0 {
+			fmt.Printf("Extends  %s\n", strings.Join(info.Extends, ", "))
+		}
+		if info.SecretsFile != "" {
+			fmt.Printf("Secrets  %s\n", info.SecretsFile)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\nSETTING\tVALUE\tSOURCE")
+		for _, s := range info.Settings {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+		}
+		fmt.Fprintln(w, "\nVARIABLE\tVALUE\tSOURCE")
+		for _, s := range info.Vars {
+			if s.Key == "PATH" {
+				s.Value = "see PATH changes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+		}
+		w.Flush()
+		if len(info.PathAdded)+len(info.PathRemove) > 0 {
+			fmt.Println("\nPATH changes")
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, p := range info.PathAdded {
+				fmt.Fprintf(w, "  + %s\t%s\n", p, info.PathSources[p])
+			}
+			for _, p := range info.PathRemove {
+				fmt.Fprintf(w, "  - %s\t%s\n", p, info.PathSources[p])
+			}
+			w.Flush()
+		}
+	},
+}
+
+//getProjectInfo describes cfg with the sources recorded when it was loaded
+func getProjectInfo(cfg *shellConfig) *projectInfo {
+	info := &projectInfo{
+		Project:    cfg.ProjectName,
+		Path:       cfg.ProjectPath,
+		ConfigFile: cfg.ConfigFile,
+		Extends:    append([]string{}, cfg.Extends...),
+		Settings:   []setting{},
+		Vars:       []setting{},
+	}
+	for _, key := range []string{"root", "goprivate", "go111module"} {
+		info.Settings = append(info.Settings, setting{Key: key, Value: viper.GetString(key), Source: settingSource(key)})
+	}
+	for _, v := range cfg.Vars() {
+		source := cfg.Sources[v.Key]
+		if source == "" {
+			source = "gopr"
+		}
+		info.Vars = append(info.Vars, setting{Key: v.Key, Value: v.Value, Source: source})
+	}
+	info.PathAdded, info.PathRemove = listDiff(os.Getenv("PATH"), cfg.Path)
+	info.PathSources = map[string]string{}
+	for _, p := range append(append([]string{}, info.PathAdded...), info.PathRemove...) {
+		source := cfg.PathSources[p]
+		if source == "" {
+			source = "gopr"
+		}
+		info.PathSources[p] = source
+	}
+	if f := filepath.Join(cfg.ProjectPath, secretsFile); exists(f) {
+		info.SecretsFile = f
+		info.Settings = append(info.Settings, setting{Key: identityFileKey, Value: identityFile(), Source: settingSource(identityFileKey)})
+	}
+	return info
+}
+
+//listDiff returns the entries in the path list next that are not in prev
+//and the ones in prev that are not in next
+func listDiff(prev, next string) (added, removed []string) {
+	p, n := pathlist.Split(prev), pathlist.Split(next)
+	added, removed = []string{}, []string{}
+	for _, e := range n {
+		if pathlist.Index(p, e) < 0 {
+			added = append(added, e)
+		}
+	}
+	for _, e := range p {
+		if pathlist.Index(n, e) < 0 {
+			removed = append(removed, e)
+		}
+	}
+	return added, removed
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "output as JSON")
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDiff(t *testing.T) {
+	join := func(s ...string) string { return strings.Join(s, string(os.PathListSeparator)) }
+	added, removed := listDiff(join("/usr/bin", "/old/go/bin", "/bin"), join("/new/go/bin", "/usr/bin", "/bin"))
+	assert.Equal(t, []string{"/new/go/bin"}, added)
+	assert.Equal(t, []string{"/old/go/bin"}, removed)
+
+	added, removed = listDiff(join("/bin"), join("/bin"))
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
+
+func TestSettingSource(t *testing.T) {
+	assert.Equal(t, "default", settingSource("goprivate"))
+
+	dir, err := ioutil.TempDir("", "gopr")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, ".gopr.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("goprivate: example.com\n"), 0644))
+	viper.SetConfigFile(file)
+	assert.NoError(t, viper.ReadInConfig())
+	defer func() {
+		ioutil.WriteFile(file, nil, 0644)
+		viper.ReadInConfig()
+	}()
+	assert.Equal(t, "config "+file, settingSource("goprivate"))
+
+	os.Setenv("GOPRIVATE", "example.net")
+	defer os.Unsetenv("GOPRIVATE")
+	assert.Equal(t, "env GOPRIVATE", settingSource("goprivate"))
+	os.Setenv("GOPR_GOPRIVATE", "example.org")
+	defer os.Unsetenv("GOPR_GOPRIVATE")
+	assert.Equal(t, "env GOPR_GOPRIVATE", settingSource("goprivate"))
+	os.Setenv("IDENTITY", "/tmp/key.txt")
+	defer os.Unsetenv("IDENTITY")
+	assert.Equal(t, "default", settingSource(identityFileKey))
+
+	f := rootCmd.PersistentFlags().Lookup("goprivate")
+	f.Changed = true
+	defer func() { f.Changed = false }()
+	assert.Equal(t, "flag --goprivate", settingSource("goprivate"))
+	assert.Equal(t, "default", settingSource("go111module"))
+}
+
+func TestProjectInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopr")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := testShellConfig("foo", map[string]string{"A": "1"})
+	cfg.ProjectPath = dir
+	cfg.ConfigFile = filepath.Join(dir, projectConfigFile)
+	cfg.Extends = []string{"base"}
+	cfg.Sources = map[string]string{
+		"GOPATH": "root from default",
+		"A":      cfg.ConfigFile,
+	}
+	cfg.Path = "/gopr-test/base:" + cfg.Path
+	cfg.PathSources = map[string]string{"/gopr-test/base": "base.yaml"}
+	info := getProjectInfo(cfg)
+	assert.Equal(t, []string{"base"}, info.Extends)
+	assert.Contains(t, info.PathAdded, "/gopr-test/base")
+	assert.Equal(t, "base.yaml", info.PathSources["/gopr-test/base"])
+	assert.Equal(t, "gopr", info.PathSources["/gopr/foo/go/bin"])
+	sources := map[string]string{}
+	for _, v := range info.Vars {
+		sources[v.Key] = v.Source
+	}
+	assert.Equal(t, map[string]string{
+		"GOPATH":      "root from default",
+		"GO111MODULE": "gopr",
+		"GOPRIVATE":   "gopr",
+		"PATH":        "gopr",
+		"A":           cfg.ConfigFile,
+		activeEnvVar:  "gopr",
+	}, sources)
+	assert.Empty(t, info.SecretsFile)
+
+	data, err := json.Marshal(info)
+	assert.NoError(t, err)
+	var out map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &out))
+	for _, key := range []string{"project", "path", "config_file", "extends", "settings", "vars", "path_added", "path_removed", "path_sources"} {
+		assert.Contains(t, out, key)
+	}
+	assert.NotContains(t, out, "secrets_file")
+	assert.Equal(t, map[string]interface{}{"key": "A", "value": "1", "source": cfg.ConfigFile}, out["vars"].([]interface{})[4])
+
+	secrets := filepath.Join(dir, secretsFile)
+	assert.NoError(t, ioutil.WriteFile(secrets, nil, 0644))
+	info = getProjectInfo(cfg)
+	assert.Equal(t, secrets, info.SecretsFile)
+	assert.Equal(t, identityFileKey, info.Settings[len(info.Settings)-1].Key)
+}
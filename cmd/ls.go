@@ -13,51 +13,194 @@ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 See the License for the specific language governing permissions and
 limitations under the License.
 */
+
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/kmpm/gopr/lib/fsutil"
 	"github.com/spf13/cobra"
 )
 
+var (
+	lsJSON bool
+	lsSort string
+
+	// lsSorters order projects for --sort, sizes and times largest first
+	lsSorters = map[string]func(a, b *projectStatus) bool{
+		"name":      func(a, b *projectStatus) bool { return a.Name < b.Name },
+		"size":      func(a, b *projectStatus) bool { return a.Size > b.Size },
+		"modcache":  func(a, b *projectStatus) bool { return a.ModCacheSize > b.ModCacheSize },
+		"bins":      func(a, b *projectStatus) bool { return a.Binaries > b.Binaries },
+		"activated": func(a, b *projectStatus) bool { return activatedTime(a).After(activatedTime(b)) },
+	}
+)
+
+//projectStatus is what ls shows about a project
+type projectStatus struct {
+	Name          string     `json:"name"`
+	Active        bool       `json:"active"`
+	Path          string     `json:"path"`
+	Size          int64      `json:"size"`
+	ModCacheSize  int64      `json:"modcache_size"`
+	Binaries      int        `json:"binaries"`
+	LastActivated *time.Time `json:"last_activated,omitempty"`
+	Go111Module   string     `json:"go111module"`
+	GoPrivate     string     `json:"goprivate"`
+	GoVersion     string     `json:"goversion,omitempty"`
+	Errors        []string   `json:"errors,omitempty"`
+}
+
 // lsCmd represents the ls command
 var lsCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List go project environments",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Long: `List go project environments with their status.
+
+The columns are the disk usage of the GOPATH, the part of it used by the
+module cache, the number of binaries in go/bin, when the project was last
+activated by env, shell, exec or a hook, the GO111MODULE and GOPRIVATE
+values and the pinned go version. The active project is marked with *.
+A project that could not be read completely is marked with ! and the
+problems are printed after the list, in the JSON output they are in
+errors.
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+Use --sort to order by ` + strings.Join(lsSortNames(), ", ") + ` and --json for
+machine readable output.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		less, ok := lsSorters[lsSort]
+		if !ok {
+			er(fmt.Sprintf("Invalid --sort '%s', use one of %s", lsSort, strings.Join(lsSortNames(), ", ")), nil)
+		}
 		list, err := projectList()
-		if err != nil {
-			fmt.Println("Error listing projects", err)
+		exitOn("Error listing projects", err)
+
+		statuses := make([]*projectStatus, 0, len(list))
+		for _, p := range list {
+			statuses = append(statuses, getProjectStatus(p))
 		}
-		if len(list) > 0 {
-			fmt.Println("Available Projects")
-			for _, p := range list {
-				fmt.Println(p)
-			}
-		} else {
+		sort.SliceStable(statuses, func(i, j int) bool { return less(statuses[i], statuses[j]) })
+
+		if lsJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			exitOn("Unexpected error", enc.Encode(statuses))
+			return
+		}
+		if len(statuses) == 0 {
 			fmt.Println("No projects available")
 			fmt.Println("Create with the 'add' command")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  NAME\tSIZE\tMODCACHE\tBINS\tACTIVATED\tGO111MODULE\tGOPRIVATE\tGO")
+		for _, s := range statuses {
+			marker := " "
+			if len(s.Errors) > 0 {
+				marker = "!"
+			} else if s.Active {
+				marker = "*"
+			}
+			activated := "never"
+			if s.LastActivated != nil {
+				activated = s.LastActivated.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(w, "%s %s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", marker, s.Name,
+				humanSize(s.Size), humanSize(s.ModCacheSize), s.Binaries, activated,
+				dash(s.Go111Module), dash(s.GoPrivate), dash(s.GoVersion))
+		}
+		w.Flush()
+		for _, s := range statuses {
+			for _, e := range s.Errors {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", s.Name, e)
+			}
 		}
 	},
 }
 
-func init() {
-	rootCmd.AddCommand(lsCmd)
+//getProjectStatus collects the status of projectName, settings are the
+//ones env would use. What could not be read is left out and the problem
+//is added to the Errors of the status.
+func getProjectStatus(projectName string) *projectStatus {
+	cfg := projectPaths(projectName)
+	s := &projectStatus{
+		Name:   projectName,
+		Active: activeProject() == projectName,
+		Path:   cfg.ProjectPath,
+	}
+	fail := func(err error) {
+		s.Errors = append(s.Errors, err.Error())
+	}
+
+	pc, err := readMergedConfig(projectName)
+	if err == nil {
+		err = cfg.Merge(pc)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		fail(err)
+	}
+	s.Go111Module = cfg.Go111Module
+	s.GoPrivate = cfg.GoPrivate
+	s.GoVersion = cfg.GoVersion
+
+	if s.Size, err = fsutil.DirSize(cfg.GoPath); err != nil {
+		fail(err)
+	}
+	if modcache := filepath.Join(cfg.GoPath, "pkg", "mod"); exists(modcache) {
+		if s.ModCacheSize, err = fsutil.DirSize(modcache); err != nil {
+			fail(err)
+		}
+	}
+	bins, _ := ioutil.ReadDir(filepath.Join(cfg.GoPath, "bin"))
+	s.Binaries = len(bins)
+	if t := lastActivated(cfg.ProjectPath); !t.IsZero() {
+		s.LastActivated = &t
+	}
+	return s
+}
+
+func activatedTime(s *projectStatus) time.Time {
+	if s.LastActivated == nil {
+		return time.Time{}
+	}
+	return *s.LastActivated
+}
+
+func lsSortNames() []string {
+	names := make([]string, 0, len(lsSorters))
+	for n := range lsSorters {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
 
-	// Here you will define your flags and configuration settings.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// lsCmd.PersistentFlags().String("foo", "", "A help for foo")
+func init() {
+	rootCmd.AddCommand(lsCmd)
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// lsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	lsCmd.Flags().BoolVar(&lsJSON, "json", false, "output as JSON")
+	lsCmd.Flags().StringVar(&lsSort, "sort", "name", "sort by "+strings.Join(lsSortNames(), ", "))
 }
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProjectStatus(t *testing.T) {
+	cleanup := testProjects(t, map[string]string{
+		"good":   "version: 3\ngoprivate: example.com\n",
+		"bare":   "",
+		"broken": "version: 3\ngoprivate: [\n",
+		"nogo":   "version: 3\ngoprivate: example.org\n",
+	}, nil)
+	defer cleanup()
+	assert.NoError(t, os.RemoveAll(filepath.Join(projectsRoot, "nogo", "go")))
+
+	s := getProjectStatus("good")
+	assert.Empty(t, s.Errors)
+	assert.Equal(t, "example.com", s.GoPrivate)
+	assert.Empty(t, getProjectStatus("bare").Errors)
+
+	s = getProjectStatus("broken")
+	assert.Len(t, s.Errors, 1)
+	assert.Empty(t, s.GoPrivate)
+
+	// the other projects are still listed
+	s = getProjectStatus("nogo")
+	assert.NotEmpty(t, s.Errors)
+	assert.Equal(t, filepath.Join(projectsRoot, "nogo"), s.Path)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kmpm/gopr/lib/project"
+	"github.com/spf13/cobra"
+)
+
+// mvCmd represents the mv command
+var mvCmd = &cobra.Command{
+	Use:   "mv <project> <new name>",
+	Short: "Rename a go project environment",
+	Long: `Rename a go project environment.
+
+The project directory is renamed and absolute paths to it in the env,
+path and pathlists of project.yaml are rewritten, keeping the comments,
+including the paths of secret://file/ references. The extends of other projects and profiles that name the project are
+updated the same way. Binaries in go/bin that contain the old path are
+listed since they may need to be reinstalled. The currently active
+project can not be renamed and the new name must not be in use.
+
+Files that refer to the project by name, like .gopr.yaml and .envrc, are
+not changed.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		oldName, newName := args[0], args[1]
+
+		found, err := projectExists(oldName)
+		exitOn("Can not list projects", err)
+		if !found {
+			exitOn("Invalid project", fmt.Errorf("project '%s' not in list", oldName))
+		}
+		exitOn("Invalid project name", checkProjectName(newName))
+		if activeProject() == oldName {
+			er(fmt.Sprintf("Project '%s' is active in this shell, deactivate it first", oldName), nil)
+		}
+
+		oldCfg, newCfg := projectPaths(oldName), projectPaths(newName)
+		if _, err := os.Lstat(newCfg.ProjectPath); !os.IsNotExist(err) {
+			er(fmt.Sprintf("Project path '%s' exists", newCfg.ProjectPath), nil)
+		}
+
+		err = os.Rename(oldCfg.ProjectPath, newCfg.ProjectPath)
+		exitOn("Could not rename project", err)
+		fmt.Printf("Renamed %s to %s\n", oldCfg.ProjectPath, newCfg.ProjectPath)
+
+		if _, err := os.Stat(newCfg.ConfigFile); err == nil {
+			if doc, err := project.ReadConfigDocument(newCfg.ConfigFile); err == nil {
+				if n := doc.RewritePaths(oldCfg.ProjectPath, newCfg.ProjectPath); n > 0 {
+					err = doc.Write(newCfg.ConfigFile)
+					exitOn("Could not update project configuration", err)
+					fmt.Printf("Updated %d paths in %s\n", n, newCfg.ConfigFile)
+				}
+			} else {
+				fmt.Printf("Could not read %s: %v\n", newCfg.ConfigFile, err)
+			}
+		}
+
+		children, err := extendingConfigs(oldName)
+		exitOn("Can not list projects", err)
+		for _, f := range children {
+			doc, err := project.ReadConfigDocument(f)
+			if err == nil && doc.RenameExtends(oldName, newName) > 0 {
+				err = doc.Write(f)
+			}
+			if err != nil {
+				fmt.Printf("Could not update extends in %s: %v\n", f, err)
+				continue
+			}
+			fmt.Printf("Updated extends in %s\n", f)
+		}
+
+		stale := binariesContaining(filepath.Join(newCfg.GoPath, "bin"), oldCfg.ProjectPath)
+		if len(stale) > 0 {
+			fmt.Println("Warning: these binaries contain the old project path and may need to be reinstalled")
+			for _, b := range stale {
+				fmt.Println("  " + b)
+			}
+		}
+	},
+}
+
+//binariesContaining returns the files in dir whose content contains s
+func binariesContaining(dir, s string) []string {
+	found := []string{}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return found
+	}
+	for _, f := range files {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err == nil && bytes.Contains(data, []byte(s)) {
+			found = append(found, f.Name())
+		}
+	}
+	return found
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+}
@@ -17,35 +17,75 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
+	"github.com/kmpm/gopr/lib/fsutil"
 	"github.com/spf13/cobra"
 )
 
+var rmYes bool
+
 // rmCmd represents the rm command
 var rmCmd = &cobra.Command{
-	Use:   "rm",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Use:   "rm <project>",
+	Short: "Remove a go project environment",
+	Long: `Remove a go project environment and everything in it.
+
+This deletes the whole project directory including GOPATH, module cache,
+installed binaries and project.yaml. A summary is shown, with the projects
+and profiles that extend the project, and you are asked for confirmation
+unless --yes is given. The currently active project can not be removed.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("rm called")
+		if len(args) != 1 {
+			er("You must provide a project name", nil)
+		}
+		projectName := args[0]
+
+		found, err := projectExists(projectName)
+		exitOn("Can not list projects", err)
+		if !found {
+			exitOn("Invalid project", fmt.Errorf("project '%s' not in list", projectName))
+		}
+
+		if activeProject() == projectName {
+			er(fmt.Sprintf("Project '%s' is active in this shell, deactivate it first", projectName), nil)
+		}
+
+		cfg := projectPaths(projectName)
+		size, err := fsutil.DirSize(cfg.GoPath)
+		exitOn("Could not calculate GOPATH size", err)
+
+		fmt.Printf("Project '%s' at %s\n", projectName, cfg.ProjectPath)
+		fmt.Printf("  GOPATH     %s (%s)\n", cfg.GoPath, humanSize(size))
+		if _, err := os.Stat(cfg.ConfigFile); err == nil {
+			fmt.Printf("  config     %s\n", cfg.ConfigFile)
+		}
+		bins, _ := ioutil.ReadDir(filepath.Join(cfg.GoPath, "bin"))
+		fmt.Printf("  binaries   %d\n", len(bins))
+		children, err := extendingConfigs(projectName)
+		exitOn("Can not list projects", err)
+		if len(children) > 0 {
+			fmt.Println("Warning: these configs extend the project and can not be loaded once it is removed")
+			for _, f := range children {
+				fmt.Println("  " + f)
+			}
+		}
+
+		if !rmYes && !confirm("Delete this project?") {
+			fmt.Println("Aborted")
+			os.Exit(1)
+		}
+
+		err = fsutil.RemoveAll(cfg.ProjectPath)
+		exitOn("Could not remove project", err)
+		fmt.Println("Removed", cfg.ProjectPath)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(rmCmd)
 
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// rmCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// rmCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rmCmd.Flags().BoolVarP(&rmYes, "yes", "y", false, "do not ask for confirmation")
 }
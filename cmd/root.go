@@ -19,13 +19,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/kmpm/gopr/lib/project"
 	"github.com/spf13/cobra"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
 
+// envPrefix is the prefix of environment variables with settings
+const envPrefix = "gopr"
+
+// unprefixedEnvKeys are the settings that are still read from the
+// environment without envPrefix, like ROOT, as gopr did before the prefix
+var unprefixedEnvKeys = map[string]bool{"root": true, "goprivate": true, "go111module": true}
+
 var cfgFile string
 var projectsRoot string
 var userHome string
@@ -46,6 +55,10 @@ to quickly create a Cobra application.`,
 		viperGetStringP(&projectsRoot, "root")
 		viperGetStringP(&defaultGOPRIVATE, "goprivate")
 		viperGetStringP(&defaultGO111MODULE, "go111module")
+		// config has to work to correct an invalid value
+		if !isConfigCmd(cmd) {
+			exitOn("Invalid setting", project.CheckGoEnv("go111module", defaultGO111MODULE))
+		}
 	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -98,7 +111,14 @@ func initConfig() {
 		viper.SetConfigName(".gopr")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// read in environment variables that match, like GOPR_ROOT
+	viper.SetEnvPrefix(envPrefix)
+	viper.AutomaticEnv()
+	for key := range unprefixedEnvKeys {
+		if env, ok := settingEnv(key); ok {
+			viper.BindEnv(key, env)
+		}
+	}
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
@@ -107,6 +127,31 @@ func initConfig() {
 
 }
 
+//settingEnv returns the environment variable that is set for the global
+//setting key, GOPR_<KEY> or else <KEY> for the unprefixedEnvKeys
+func settingEnv(key string) (string, bool) {
+	names := []string{strings.ToUpper(envPrefix + "_" + key)}
+	if unprefixedEnvKeys[key] {
+		names = append(names, strings.ToUpper(key))
+	}
+	for _, env := range names {
+		if _, ok := os.LookupEnv(env); ok {
+			return env, true
+		}
+	}
+	return "", false
+}
+
+//isConfigCmd reports whether cmd is config or one of its subcommands
+func isConfigCmd(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd.Name() == "config" {
+			return true
+		}
+	}
+	return false
+}
+
 func viperGetStringP(p *string, name string) string {
 	v := viper.GetString(name)
 	if v != "" {
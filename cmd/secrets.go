@@ -0,0 +1,287 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/kmpm/gopr/lib/envfile"
+	"github.com/kmpm/gopr/lib/secret"
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	secretsFile     string = "project.env.age"
+	identityFileKey string = "identity"
+)
+
+var (
+	secretsProject string
+	// ageSecrets are the decrypted variables of the project being set up,
+	// they are what secret://age/<name> refers to
+	ageSecrets = map[string]string{}
+)
+
+// secretsCmd represents the secrets command
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted variables of a project",
+	Long: `Manage the variables kept encrypted with age in the project.env.age file
+of a project. The file can be committed along with project.yaml without
+leaking the values.
+
+env, exec, shell and the hooks decrypt the file with the identity in
+~/.gopr-identity.txt, or the file set with
+'gopr config --global set identity <file>', and set its variables after
+the ones in project.yaml and .gopr.yaml. Other env values can refer to
+them as secret://age/<name>. info never shows them.
+
+If there is no identity one is created the first time a value is set.
+Keep a copy of it, without it the file can not be decrypted. set, rm and
+edit encrypt the file again to the identities in your identity file only,
+anyone else it was encrypted to can no longer decrypt it afterwards.
+
+The project is the one given with --project, or the one named by a
+.gopr.yaml in the current directory or one of its parents, or the active
+project.`,
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set <name> [value]",
+	Short: "Set an encrypted variable, the value is read from stdin if not given",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		exitOn("Invalid variable", checkVarName(name))
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			v, err := readValue(name)
+			exitOn("Could not read value", err)
+			value = v
+		}
+
+		filename, vars, ids := readSecrets(true)
+		set := false
+		for i := range vars {
+			if vars[i].Key == name {
+				vars[i].Value, set = value, true
+			}
+		}
+		if !set {
+			vars = append(vars, envVar{Key: name, Value: value})
+		}
+		exitOn("Could not write secrets", writeSecrets(filename, vars, ids))
+	},
+}
+
+var secretsRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove an encrypted variable",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		filename, vars, ids := readSecrets(false)
+		kept := make([]envVar, 0, len(vars))
+		for _, v := range vars {
+			if v.Key != name {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == len(vars) {
+			er(fmt.Sprintf("%s is not set in %s", name, filename), nil)
+		}
+		exitOn("Could not write secrets", writeSecrets(filename, kept, ids))
+	},
+}
+
+var secretsEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the decrypted variables in an editor",
+	Long: `Decrypt the variables to a temporary file, readable only by you, and open
+it in $VISUAL or $EDITOR. The file uses the .env syntax, NAME=value with
+the value in single quotes to keep it as it is. It is encrypted again and
+the temporary file removed when the editor exits.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename, vars, ids := readSecrets(true)
+
+		tmp, err := ioutil.TempFile("", "gopr-secrets-*.env")
+		exitOn("Could not create temporary file", err)
+		defer os.Remove(tmp.Name())
+		err = envfile.Write(tmp, "dotenv", vars)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = runEditor(tmp.Name(), func(f string) error {
+				vars, err = readDotenvFile(f)
+				return err
+			})
+		}
+		if err == nil {
+			err = writeSecrets(filename, vars, ids)
+		}
+		if err != nil {
+			// exiting skips the deferred remove
+			os.Remove(tmp.Name())
+			er("Could not edit secrets", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsSetCmd, secretsRmCmd, secretsEditCmd)
+
+	secretsCmd.PersistentFlags().StringVarP(&secretsProject, "project", "p", "", "the project to manage")
+	// values starting with - are not flags
+	secretsSetCmd.Flags().SetInterspersed(false)
+
+	secret.Register("age", secret.ProviderFunc(func(name string) (string, error) {
+		v, ok := ageSecrets[name]
+		if !ok {
+			return "", fmt.Errorf("%s is not set in %s", name, secretsFile)
+		}
+		return v, nil
+	}))
+}
+
+//identityFile returns the file with the age identities used for
+//secretsFile
+func identityFile() string {
+	if f := viper.GetString(identityFileKey); f != "" {
+		return f
+	}
+	return filepath.Join(userHome, ".gopr-identity.txt")
+}
+
+//readIdentities reads the identities of the user. With create a missing
+//identity file is created.
+func readIdentities(create bool) ([]age.Identity, error) {
+	filename := identityFile()
+	ids, err := secret.ReadIdentities(filename)
+	if os.IsNotExist(err) && create {
+		id, err := secret.GenerateIdentity(filename)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Created the identity %s with the public key %s, keep a copy of it\n", filename, id.Recipient())
+		return []age.Identity{id}, nil
+	}
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no identity in %s, set one with 'gopr config --global set identity <file>'", filename)
+	}
+	return ids, err
+}
+
+//readSecrets exits unless the secretsFile of the project secrets works
+//on, if it exists, and the identities can be read
+func readSecrets(create bool) (string, []envVar, []age.Identity) {
+	name := targetProject(secretsProject, "use --project")
+	filename := filepath.Join(projectsRoot, name, secretsFile)
+	ids, err := readIdentities(create)
+	exitOn("Could not read identity", err)
+	vars, err := secret.ReadEnvFile(filename, ids)
+	if os.IsNotExist(err) {
+		return filename, []envVar{}, ids
+	}
+	exitOn("Could not decrypt secrets", err)
+	return filename, vars, ids
+}
+
+//writeSecrets encrypts vars to filename for ids and warns if the file
+//was encrypted to recipients that are not among them
+func writeSecrets(filename string, vars []envVar, ids []age.Identity) error {
+	recipients := secret.Recipients(ids)
+	if n, err := secret.CountRecipients(filename); err == nil && n > len(recipients) {
+		fmt.Fprintf(os.Stderr, "gopr: %s was encrypted to %d recipients, it is now only encrypted to the %d in %s\n",
+			filename, n, len(recipients), identityFile())
+	}
+	return secret.WriteEnvFile(filename, vars, recipients)
+}
+
+//readSecretsFile returns the variables of the secretsFile in projectPath,
+//none if there is no such file, and makes them available to the age
+//secret provider
+func readSecretsFile(projectPath string) ([]envVar, string, error) {
+	filename := filepath.Join(projectPath, secretsFile)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, filename, nil
+	}
+	ids, err := readIdentities(false)
+	if err != nil {
+		return nil, filename, err
+	}
+	vars, err := secret.ReadEnvFile(filename, ids)
+	if err != nil {
+		return nil, filename, err
+	}
+	for _, v := range vars {
+		ageSecrets[v.Key] = v.Value
+	}
+	return vars, filename, nil
+}
+
+//readDotenvFile parses filename as a .env file without duplicate names
+func readDotenvFile(filename string) ([]envVar, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vars, err := envfile.ReadDotenv(f)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if seen[v.Key] {
+			return nil, fmt.Errorf("%s is set twice", v.Key)
+		}
+		seen[v.Key] = true
+	}
+	return vars, nil
+}
+
+//checkVarName returns an error if name can not be a variable
+func checkVarName(name string) error {
+	if !shell.ValidName(name) {
+		return fmt.Errorf("%w: %q", shell.ErrInvalidName, name)
+	}
+	return nil
+}
+
+//readValue reads the value of name from stdin, asking for it when stdin
+//is a terminal. A trailing newline is dropped.
+func readValue(name string) (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), err
+}
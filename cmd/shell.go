@@ -0,0 +1,174 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/spf13/cobra"
+)
+
+var shellStack bool
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:   "shell [project]",
+	Short: "Start a new shell with the go project environment",
+	Long: `Start a new instance of your shell with the environment of the project
+set up, the same as the env command would. The prompt is prefixed with the
+project name and you leave the project by exiting the shell.
+
+Without a project name the project is found from a .gopr.yaml in the
+current directory or one of its parents.
+
+Starting a project shell from within another project is refused unless
+--stack is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if active := activeProject(); active != "" && !shellStack {
+			er(fmt.Sprintf("Project '%s' is already active, exit that shell first or use --stack", active), nil)
+		}
+
+		cfg, err := projectFromArgs(args)
+		exitOn("Invalid project", err)
+		exitOn("Could not resolve secrets", cfg.ResolveSecrets())
+		projectName := cfg.ProjectName
+
+		name, err := getShell(userShell)
+		exitOn("Could not detect shell", err)
+
+		sub, err := newSubshell(name, fmt.Sprintf("(%s) ", projectName))
+		exitOn("Could not prepare shell", err)
+
+		markActivated(cfg)
+		fmt.Printf("Entering project '%s', exit the shell to leave it\n", projectName)
+		code, err := runCommand(sub.path, sub.args, append(cfg.Environ(os.Environ()), sub.env...))
+		sub.cleanup()
+		exitOn("Could not start shell", err)
+		os.Exit(code)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+
+	shellCmd.Flags().StringVar(&userShell, "shell", "", "set custom shell")
+	shellCmd.Flags().BoolVar(&shellStack, "stack", false, "allow starting from within another project")
+}
+
+//subshell describes how to start an interactive shell with a modified prompt
+type subshell struct {
+	path string
+	args []string
+	env  []string
+	tmp  string
+}
+
+func (s *subshell) cleanup() {
+	if s.tmp != "" {
+		os.RemoveAll(s.tmp)
+	}
+}
+
+//newSubshell prepares to start the shell called name with prompt added in
+//front of the users own prompt
+func newSubshell(name, prompt string) (*subshell, error) {
+	path, err := shellPath(name)
+	if err != nil {
+		return nil, err
+	}
+	s := &subshell{path: path}
+
+	switch name {
+	case "bash":
+		if s.tmp, err = ioutil.TempDir("", "gopr"); err != nil {
+			return nil, err
+		}
+		rc := filepath.Join(s.tmp, "bashrc")
+		script := "if [ -f ~/.bashrc ]; then . ~/.bashrc; fi\n" +
+			"PS1=" + quote("sh", prompt) + "\"$PS1\"\n"
+		if err = ioutil.WriteFile(rc, []byte(script), 0600); err != nil {
+			s.cleanup()
+			return nil, err
+		}
+		s.args = []string{"--rcfile", rc, "-i"}
+	case "zsh":
+		if s.tmp, err = ioutil.TempDir("", "gopr"); err != nil {
+			return nil, err
+		}
+		orig := os.Getenv("ZDOTDIR")
+		if orig == "" {
+			orig = userHome
+		}
+		files := map[string]string{
+			".zshenv": "ZDOTDIR=" + quote("sh", orig) + "\n" +
+				"if [ -f \"$ZDOTDIR/.zshenv\" ]; then . \"$ZDOTDIR/.zshenv\"; fi\n" +
+				"ZDOTDIR=" + quote("sh", s.tmp) + "\n",
+			".zshrc": "ZDOTDIR=" + quote("sh", orig) + "\n" +
+				"if [ -f \"$ZDOTDIR/.zshrc\" ]; then . \"$ZDOTDIR/.zshrc\"; fi\n" +
+				"PROMPT=" + quote("sh", prompt) + "\"$PROMPT\"\n",
+		}
+		for f, script := range files {
+			if err = ioutil.WriteFile(filepath.Join(s.tmp, f), []byte(script), 0600); err != nil {
+				s.cleanup()
+				return nil, err
+			}
+		}
+		s.env = []string{"ZDOTDIR=" + s.tmp}
+	case "fish":
+		s.args = []string{"-C", "functions -c fish_prompt __gopr_fish_prompt; " +
+			"function fish_prompt; printf '%s' " + quote("fish", prompt) + "; __gopr_fish_prompt; end"}
+	case "powershell", "pwsh":
+		s.args = []string{"-NoExit", "-Command", "$__gopr_prompt = $function:prompt; " +
+			"function global:prompt { " + quote("powershell", prompt) + " + (& $__gopr_prompt) }"}
+	case "cmd":
+		s.env = []string{"PROMPT=" + prompt + "$P$G"}
+	default:
+		ps1 := os.Getenv("PS1")
+		if ps1 == "" {
+			ps1 = "$ "
+		}
+		s.env = []string{"PS1=" + prompt + ps1}
+	}
+	return s, nil
+}
+
+//shellPath finds the executable for the shell called name
+func shellPath(name string) (string, error) {
+	if sh := os.Getenv("SHELL"); sh != "" && strings.TrimSuffix(filepath.Base(sh), ".exe") == name {
+		return sh, nil
+	}
+	if name == "cmd" {
+		if comspec := os.Getenv("ComSpec"); comspec != "" {
+			return comspec, nil
+		}
+	}
+	return exec.LookPath(name)
+}
+
+//quote returns s as a string literal in the shell called name
+func quote(name, s string) string {
+	sh, err := shell.Get(name)
+	if err != nil {
+		sh, _ = shell.Get("sh")
+	}
+	return sh.Quote(s)
+}
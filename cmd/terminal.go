@@ -17,7 +17,11 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"os"
 	"runtime"
+	"strings"
 
 	"github.com/kmpm/gopr/lib/shell"
 )
@@ -32,3 +36,17 @@ func getShell(userShell string) (string, error) {
 func runtimeOS() string {
 	return runtime.GOOS
 }
+
+//confirm asks a yes/no question on stdout and reads the answer from stdin
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
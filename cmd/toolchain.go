@@ -0,0 +1,141 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/toolchain"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	toolchainFile   string
+	toolchainSHA256 string
+	toolchainForce  bool
+)
+
+// toolchainCmd represents the toolchain command
+var toolchainCmd = &cobra.Command{
+	Use:   "toolchain",
+	Short: "Manage go SDKs that projects can pin with goversion",
+	Long: `Manage the shared cache of go SDKs kept in the toolchains directory
+under the projects root.
+
+A project selects a version by setting goversion in its project.yaml. The
+env command then puts that SDK first on PATH and sets GOROOT.`,
+}
+
+var toolchainInstallCmd = &cobra.Command{
+	Use:   "install <version>",
+	Short: "Download and install a go SDK",
+	Long: `Download and install a go SDK into the toolchain cache.
+
+By default the official distribution is downloaded from ` + toolchain.DefaultMirror + `.
+Use --mirror to download from another server, for example a local file
+server, or --file to install from an archive on disk.
+
+The SHA256 checksum of the archive is checked against --sha256 or, without
+it, against the <archive>.sha256 file the mirror publishes next to the
+archive. An archive on disk is checked when there is a .sha256 file next
+to it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version := toolchain.Normalize(args[0])
+		if !toolchain.Valid(version) {
+			exitOn("Can not install", toolchain.ErrInvalidVersion)
+		}
+		source := toolchainFile
+		if source == "" {
+			source = viper.GetString("mirror")
+			fmt.Printf("Downloading %s from %s\n", toolchain.Archive(version), source)
+		}
+		err := toolchain.Install(toolchainsRoot(), version, source, toolchainSHA256)
+		exitOn("Could not install "+version, err)
+		fmt.Println("Installed", toolchain.Dir(toolchainsRoot(), version))
+	},
+}
+
+var toolchainLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List installed go SDKs",
+	Run: func(cmd *cobra.Command, args []string) {
+		versions, err := toolchain.List(toolchainsRoot())
+		exitOn("Error listing toolchains", err)
+		if len(versions) == 0 {
+			fmt.Println("No toolchains installed")
+			fmt.Println("Install with the 'toolchain install' command")
+			return
+		}
+		users := toolchainUsers()
+		fmt.Println("Installed Toolchains")
+		for _, v := range versions {
+			if len(users[v]) > 0 {
+				fmt.Printf("%s\t(used by %s)\n", v, strings.Join(users[v], ", "))
+			} else {
+				fmt.Println(v)
+			}
+		}
+	},
+}
+
+var toolchainRmCmd = &cobra.Command{
+	Use:   "rm <version>",
+	Short: "Remove an installed go SDK",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version := toolchain.Normalize(args[0])
+		if users := toolchainUsers()[version]; len(users) > 0 && !toolchainForce {
+			er(fmt.Sprintf("%s is used by %s, use --force to remove anyway", version, strings.Join(users, ", ")), nil)
+		}
+		err := toolchain.Remove(toolchainsRoot(), version)
+		exitOn("Could not remove "+version, err)
+		fmt.Println("Removed", version)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(toolchainCmd)
+	toolchainCmd.AddCommand(toolchainInstallCmd)
+	toolchainCmd.AddCommand(toolchainLsCmd)
+	toolchainCmd.AddCommand(toolchainRmCmd)
+
+	toolchainInstallCmd.Flags().StringVar(&toolchainFile, "file", "", "install from a local archive")
+	toolchainInstallCmd.Flags().StringVar(&toolchainSHA256, "sha256", "", "expected SHA256 checksum of the archive")
+	toolchainInstallCmd.Flags().String("mirror", toolchain.DefaultMirror, "base url to download go distributions from")
+	viper.BindPFlag("mirror", toolchainInstallCmd.Flags().Lookup("mirror"))
+	toolchainRmCmd.Flags().BoolVarP(&toolchainForce, "force", "f", false, "remove even if projects use it")
+}
+
+//toolchainUsers maps go versions to the projects that pin them
+func toolchainUsers() map[string][]string {
+	users := make(map[string][]string)
+	list, err := projectList()
+	if err != nil {
+		return users
+	}
+	for _, name := range list {
+		pc, err := readMergedConfig(name)
+		if err != nil || pc.GoVersion == "" {
+			continue
+		}
+		v := toolchain.Normalize(pc.GoVersion)
+		users[v] = append(users[v], name)
+	}
+	return users
+}
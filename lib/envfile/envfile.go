@@ -0,0 +1,234 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfile
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/shell"
+)
+
+// Var is a single environment variable
+type Var struct {
+	Key   string
+	Value string
+}
+
+// Formats maps the name of each output format to its writer
+var Formats = map[string]func(io.Writer, []Var) error{
+	"json":            writeJSON,
+	"dotenv":          writeDotenv,
+	"systemd":         writeSystemd,
+	"docker-env-file": writeDocker,
+	"github-actions":  writeGitHub,
+}
+
+var (
+	// ErrUnknownFormat - There is no writer for the format
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrInvalidValue - The value can not be represented in the format
+	ErrInvalidValue = errors.New("invalid variable value")
+	// ErrSyntax - A line of a dotenv file can not be parsed
+	ErrSyntax = errors.New("syntax error")
+)
+
+//Write vars to w in format
+func Write(w io.Writer, format string, vars []Var) error {
+	fn, ok := Formats[format]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	for _, v := range vars {
+		if !shell.ValidName(v.Key) {
+			return fmt.Errorf("%w: %q", shell.ErrInvalidName, v.Key)
+		}
+		if strings.ContainsRune(v.Value, 0) {
+			return fmt.Errorf("%w: %s contains a NUL character", ErrInvalidValue, v.Key)
+		}
+	}
+	return fn(w, vars)
+}
+
+//writeJSON writes a single object with the variables as string members
+func writeJSON(w io.Writer, vars []Var) error {
+	m := make(map[string]string, len(vars))
+	for _, v := range vars {
+		m[v.Key] = v.Value
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+//writeDotenv uses single quotes, which all common dotenv parsers read
+//literally, when possible and escaped double quotes otherwise. In double
+//quotes $ is escaped too, as most parsers expand variables there.
+func writeDotenv(w io.Writer, vars []Var) error {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+	for _, v := range vars {
+		var err error
+		if strings.ContainsAny(v.Value, "'\r\n") {
+			_, err = fmt.Fprintf(w, "%s=\"%s\"\n", v.Key, r.Replace(v.Value))
+		} else {
+			_, err = fmt.Fprintf(w, "%s='%s'\n", v.Key, v.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//writeSystemd writes a file for EnvironmentFile= where double quoted
+//values may span lines and backslash escapes the special characters
+func writeSystemd(w io.Writer, vars []Var) error {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", "$", `\$`)
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(w, "%s=\"%s\"\n", v.Key, r.Replace(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//writeDocker writes a file for docker run --env-file which takes every
+//value literally and has no way to express line breaks
+func writeDocker(w io.Writer, vars []Var) error {
+	for _, v := range vars {
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return fmt.Errorf("%w: %s contains a line break", ErrInvalidValue, v.Key)
+		}
+	}
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Key, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//writeGitHub writes the format of the $GITHUB_ENV file, values with line
+//breaks are written as a heredoc with a random delimiter
+func writeGitHub(w io.Writer, vars []Var) error {
+	for _, v := range vars {
+		var err error
+		if strings.ContainsAny(v.Value, "\r\n") {
+			delim, derr := delimiter(v.Value)
+			if derr != nil {
+				return derr
+			}
+			_, err = fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", v.Key, delim, v.Value, delim)
+		} else {
+			_, err = fmt.Fprintf(w, "%s=%s\n", v.Key, v.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//delimiter returns a heredoc delimiter that does not occur in value
+func delimiter(value string) (string, error) {
+	b := make([]byte, 16)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		delim := "ghadelimiter_" + hex.EncodeToString(b)
+		if !strings.Contains(value, delim) {
+			return delim, nil
+		}
+	}
+}
+
+//ReadDotenv parses a .env file like the ones writeDotenv writes. Blank
+//lines and lines starting with # are skipped and an export in front of
+//the name is allowed. Single quoted values are literal, double quoted
+//values may use \\, \", \n and \r, unquoted values are used as they
+//are without surrounding whitespace.
+func ReadDotenv(r io.Reader) ([]Var, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	vars := []Var{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: %w, expected NAME=value", i+1, ErrSyntax)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !shell.ValidName(key) {
+			return nil, fmt.Errorf("line %d: %w: %q", i+1, shell.ErrInvalidName, key)
+		}
+		value, err := dotenvValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		vars = append(vars, Var{Key: key, Value: value})
+	}
+	return vars, nil
+}
+
+func dotenvValue(s string) (string, error) {
+	if s == "" || (s[0] != '\'' && s[0] != '"') {
+		return s, nil
+	}
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("%w, unterminated quote", ErrSyntax)
+	}
+	if s[0] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	var b strings.Builder
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '"' {
+			return "", fmt.Errorf("%w, unescaped quote", ErrSyntax)
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(body) {
+			return "", fmt.Errorf("%w, unterminated escape", ErrSyntax)
+		}
+		switch body[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	return b.String(), nil
+}
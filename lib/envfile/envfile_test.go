@@ -0,0 +1,85 @@
+package envfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/stretchr/testify/assert"
+)
+
+var testVars = []Var{
+	{"GOPATH", "/home/user/.gopr/foo/go"},
+	{"EMPTY", ""},
+	{"QUOTED", `it's "$HOME" \ ` + "`id`"},
+}
+
+func write(t *testing.T, format string, vars []Var) string {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, format, vars))
+	return buf.String()
+}
+
+func TestJSON(t *testing.T) {
+	m := map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(write(t, "json", testVars)), &m))
+	assert.Equal(t, map[string]string{
+		"GOPATH": "/home/user/.gopr/foo/go",
+		"EMPTY":  "",
+		"QUOTED": `it's "$HOME" \ ` + "`id`",
+	}, m)
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		vars     []Var
+		expected string
+	}{
+		{"dotenv", testVars, "GOPATH='/home/user/.gopr/foo/go'\nEMPTY=''\nQUOTED=\"it's \\\"\\$HOME\\\" \\\\ `id`\"\n"},
+		{"dotenv", []Var{{"A", "two\nlines"}}, "A=\"two\\nlines\"\n"},
+		{"dotenv", []Var{{"A", "it's $USER and ${HOME}"}, {"B", "$PLAIN"}}, "A=\"it's \\$USER and \\${HOME}\"\nB='$PLAIN'\n"},
+		{"systemd", testVars, "GOPATH=\"/home/user/.gopr/foo/go\"\nEMPTY=\"\"\nQUOTED=\"it's \\\"\\$HOME\\\" \\\\ \\`id\\`\"\n"},
+		{"systemd", []Var{{"A", "two\nlines"}}, "A=\"two\nlines\"\n"},
+		{"docker-env-file", testVars, "GOPATH=/home/user/.gopr/foo/go\nEMPTY=\nQUOTED=it's \"$HOME\" \\ `id`\n"},
+		{"github-actions", testVars, "GOPATH=/home/user/.gopr/foo/go\nEMPTY=\nQUOTED=it's \"$HOME\" \\ `id`\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, write(t, tt.format, tt.vars), tt.format)
+	}
+}
+
+func TestGitHubMultiline(t *testing.T) {
+	out := write(t, "github-actions", []Var{{"A", "two\nlines"}})
+	assert.Regexp(t, regexp.MustCompile("^A<<(ghadelimiter_[0-9a-f]{32})\ntwo\nlines\n(ghadelimiter_[0-9a-f]{32})\n$"), out)
+}
+
+func TestInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	assert.True(t, errors.Is(Write(&buf, "yaml", testVars), ErrUnknownFormat))
+	assert.True(t, errors.Is(Write(&buf, "json", []Var{{"A-B", ""}}), shell.ErrInvalidName))
+	assert.True(t, errors.Is(Write(&buf, "dotenv", []Var{{"A", "\x00"}}), ErrInvalidValue))
+	assert.True(t, errors.Is(Write(&buf, "docker-env-file", []Var{{"A", "two\nlines"}}), ErrInvalidValue))
+	assert.Empty(t, buf.String())
+}
+
+func TestReadDotenv(t *testing.T) {
+	vars := append(testVars, Var{"A", "two\nlines\r"}, Var{"B", "it's"}, Var{"C", "it's $HOME"})
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, "dotenv", vars))
+	got, err := ReadDotenv(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, vars, got)
+
+	got, err = ReadDotenv(bytes.NewBufferString("# comment\n\nexport A = plain value \nB=\"a\\\\b\"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Var{{"A", "plain value"}, {"B", `a\b`}}, got)
+
+	for _, s := range []string{"A", "1A=x", "A='x", `A="x`, `A="a"b"`, `A="x\"`} {
+		_, err := ReadDotenv(bytes.NewBufferString(s))
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyMode is how CopyTree creates regular files
+type CopyMode string
+
+const (
+	// Copy duplicates the content of every file
+	Copy CopyMode = "copy"
+	// Hardlink links the files, both trees must be on the same file system
+	Hardlink CopyMode = "hardlink"
+	// Reflink makes copy on write clones where the file system supports it
+	Reflink CopyMode = "reflink"
+)
+
+var (
+	// ErrReflinkUnsupported - The file system or platform can not clone files
+	ErrReflinkUnsupported = errors.New("reflinks are not supported")
+	// ErrUnknownCopyMode - The CopyMode is not one of the defined modes
+	ErrUnknownCopyMode = errors.New("unknown copy mode")
+)
+
+//CopyTree recreates the directory src at dst, which must not exist.
+//Regular files are created as given by mode, symbolic links are copied
+//as links and permissions are preserved, also on read-only directories
+//like the ones in the go module cache.
+func CopyTree(src, dst string, mode CopyMode) error {
+	var file func(src, dst string, perm os.FileMode) error
+	switch mode {
+	case Copy:
+		file = copyFile
+	case Hardlink:
+		file = func(src, dst string, _ os.FileMode) error { return os.Link(src, dst) }
+	case Reflink:
+		file = reflinkFile
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownCopyMode, mode)
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s: %w", dst, os.ErrExist)
+	}
+
+	// directories are made writable until everything is copied
+	type dir struct {
+		path string
+		perm os.FileMode
+	}
+	var dirs []dir
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			dirs = append(dirs, dir{target, info.Mode().Perm()})
+			return os.Mkdir(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return file(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if cerr := os.Chmod(dirs[i].path, dirs[i].perm); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,68 @@
+package fsutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//modCache creates a read-only tree like the go module cache in dir
+func modCache(t *testing.T, dir string) string {
+	root := filepath.Join(dir, "mod")
+	mod := filepath.Join(root, "example.com", "m@v1.0.0")
+	assert.NoError(t, os.MkdirAll(mod, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(mod, "go.mod"), []byte("module example.com/m\n"), 0444))
+	assert.NoError(t, os.Symlink("go.mod", filepath.Join(mod, "link")))
+	assert.NoError(t, os.Chmod(mod, 0555))
+	return root
+}
+
+func TestCopyTree(t *testing.T) {
+	for _, mode := range []CopyMode{Copy, Hardlink, Reflink} {
+		t.Run(string(mode), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "fsutil")
+			assert.NoError(t, err)
+			defer RemoveAll(dir)
+			src := modCache(t, dir)
+			dst := filepath.Join(dir, "copy")
+
+			err = CopyTree(src, dst, mode)
+			if err == ErrReflinkUnsupported {
+				t.Skip("file system can not reflink")
+			}
+			assert.NoError(t, err)
+
+			mod := filepath.Join(dst, "example.com", "m@v1.0.0")
+			data, err := ioutil.ReadFile(filepath.Join(mod, "go.mod"))
+			assert.NoError(t, err)
+			assert.Equal(t, "module example.com/m\n", string(data))
+			link, err := os.Readlink(filepath.Join(mod, "link"))
+			assert.NoError(t, err)
+			assert.Equal(t, "go.mod", link)
+			info, err := os.Stat(mod)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0555), info.Mode().Perm())
+
+			a, _ := os.Stat(filepath.Join(src, "example.com", "m@v1.0.0", "go.mod"))
+			b, _ := os.Stat(filepath.Join(mod, "go.mod"))
+			assert.Equal(t, mode == Hardlink, os.SameFile(a, b))
+			assert.Equal(t, os.FileMode(0444), b.Mode().Perm())
+		})
+	}
+}
+
+func TestCopyTreeInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsutil")
+	assert.NoError(t, err)
+	defer RemoveAll(dir)
+	src := modCache(t, dir)
+
+	assert.Error(t, CopyTree(src, dir, Copy), "destination exists")
+	err = CopyTree(src, filepath.Join(dir, "copy"), "symlink")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUnknownCopyMode))
+}
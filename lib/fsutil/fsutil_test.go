@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "a", "b"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a", "one"), make([]byte, 10), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a", "b", "two"), make([]byte, 32), 0644))
+
+	size, err := DirSize(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), size)
+}
+
+func TestRemoveAllReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// mimic the layout of the go module cache
+	mod := filepath.Join(dir, "go", "pkg", "mod", "example.com", "m@v1.0.0")
+	assert.NoError(t, os.MkdirAll(mod, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(mod, "go.mod"), []byte("module example.com/m\n"), 0444))
+	assert.NoError(t, os.Chmod(mod, 0555))
+
+	assert.NoError(t, RemoveAll(filepath.Join(dir, "go")))
+	_, err = os.Stat(filepath.Join(dir, "go"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRemoveAllMissing(t *testing.T) {
+	assert.NoError(t, RemoveAll(filepath.Join(os.TempDir(), "gopr-does-not-exist")))
+}
@@ -0,0 +1,50 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is FICLONE from linux/fs.h
+const ficlone = 0x40049409
+
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	if cerr := out.Close(); errno == 0 {
+		return cerr
+	}
+	os.Remove(dst)
+	switch errno {
+	case syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EXDEV, syscall.EINVAL:
+		return ErrReflinkUnsupported
+	}
+	return &os.PathError{Op: "reflink", Path: dst, Err: errno}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pathlist
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var (
+	// caseInsensitive is set where the file system usually ignores case
+	caseInsensitive = runtime.GOOS == "windows"
+)
+
+//Split returns the directories in a list like PATH. Empty elements are
+//dropped.
+func Split(list string) []string {
+	elems := []string{}
+	for _, p := range filepath.SplitList(list) {
+		if p != "" {
+			elems = append(elems, p)
+		}
+	}
+	return elems
+}
+
+//Join makes a list like PATH of elems
+func Join(elems []string) string {
+	return strings.Join(elems, string(os.PathListSeparator))
+}
+
+//Equal reports whether a and b name the same directory. Only the names
+//are compared, like /usr/bin/ and /usr//bin.
+func Equal(a, b string) bool {
+	return key(a) == key(b)
+}
+
+//Under reports whether p is dir or a directory below it. Only whole path
+//elements match, /a/bc is not under /a/b.
+func Under(p, dir string) bool {
+	p, dir = key(p), key(dir)
+	if p == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(p, dir)
+}
+
+//key returns p in the form used to compare directories
+func key(p string) string {
+	p = filepath.Clean(p)
+	if caseInsensitive {
+		return strings.ToLower(p)
+	}
+	return p
+}
+
+//Index returns the index of the first element in elems equal to p, or -1
+func Index(elems []string, p string) int {
+	for i, e := range elems {
+		if Equal(e, p) {
+			return i
+		}
+	}
+	return -1
+}
+
+//Remove returns the elements of elems that are not equal to any of remove
+func Remove(elems []string, remove ...string) []string {
+	return Filter(elems, func(p string) bool { return Index(remove, p) < 0 })
+}
+
+//Filter returns the elements of elems for which keep returns true
+func Filter(elems []string, keep func(string) bool) []string {
+	kept := make([]string, 0, len(elems))
+	for _, p := range elems {
+		if keep(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+//Unique returns elems with only the first of equal elements kept
+func Unique(elems []string) []string {
+	kept := make([]string, 0, len(elems))
+	for _, p := range elems {
+		if Index(kept, p) < 0 {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+//Edit changes a list like PATH. Directories in Remove are taken out, then
+//Prepend is put first and Append last. Directories in Prepend and Append
+//are moved if they already are in the list and the result has no
+//duplicates.
+type Edit struct {
+	Prepend []string
+	Append  []string
+	Remove  []string
+}
+
+//Apply returns list changed by e
+func (e Edit) Apply(list string) string {
+	// existing entries of Append are taken out so they end up last
+	elems := append(append([]string{}, e.Prepend...), Remove(Split(list), e.Append...)...)
+	elems = append(elems, e.Append...)
+	return Join(Unique(Remove(elems, e.Remove...)))
+}
+
+//Empty reports whether e changes nothing
+func (e Edit) Empty() bool {
+	return len(e.Prepend) == 0 && len(e.Append) == 0 && len(e.Remove) == 0
+}
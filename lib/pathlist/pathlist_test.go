@@ -0,0 +1,58 @@
+package pathlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//list joins elems, written with : in the tests, with the separator of the OS
+func list(s string) string {
+	return Join(strings.Split(s, ":"))
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{"/a", "/b"}, Split(list("/a::/b:")))
+	assert.Equal(t, []string{}, Split(""))
+}
+
+func TestUnder(t *testing.T) {
+	tests := []struct {
+		p, dir string
+		want   bool
+	}{
+		{"/a/b", "/a/b", true},
+		{"/a/b/", "/a/b", true},
+		{"/a/b/bin", "/a/b", true},
+		{"/a/bc/bin", "/a/b", false},
+		{"/a", "/a/b", false},
+		{"/a/b", "/", true},
+		{"/a//b/./bin", "/a/b/", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Under(tt.p, tt.dir), "%s under %s", tt.p, tt.dir)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		edit Edit
+		list string
+		want string
+	}{
+		{"nothing", Edit{}, "/a:/b", "/a:/b"},
+		{"prepend", Edit{Prepend: []string{"/x", "/y"}}, "/a:/b", "/x:/y:/a:/b"},
+		{"append", Edit{Append: []string{"/x"}}, "/a:/b", "/a:/b:/x"},
+		{"remove exact", Edit{Remove: []string{"/a/b"}}, "/a/b:/a/bc:/a/b/bin:/a/b/", "/a/bc:/a/b/bin"},
+		{"move to front", Edit{Prepend: []string{"/b"}}, "/a:/b:/c", "/b:/a:/c"},
+		{"move to end", Edit{Append: []string{"/a/"}}, "/a:/b", "/b:/a/"},
+		{"dedupe", Edit{}, "/a:/b:/a:/b/", "/a:/b"},
+		{"remove wins", Edit{Prepend: []string{"/x"}, Remove: []string{"/x"}}, "/a", "/a"},
+		{"empty list", Edit{Append: []string{"/x"}}, "", "/x"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, list(tt.want), tt.edit.Apply(list(tt.list)), tt.name)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrNotAllowed - A LocalConfigFile has not been allowed with its current content
+	ErrNotAllowed = errors.New("not allowed")
+)
+
+//AllowList is the set of local configuration files that may be applied
+//without asking, like direnv allow does for .envrc. Each file is allowed
+//with a hash of its content so any change has to be allowed again.
+type AllowList struct {
+	// File is where the list is kept, one line per allowed file
+	File  string
+	files map[string]string
+}
+
+//ReadAllowList reads the list kept in filename, a missing file is an
+//empty list
+func ReadAllowList(filename string) (*AllowList, error) {
+	l := &AllowList{File: filename, files: map[string]string{}}
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a hash and a file", filename, n)
+		}
+		l.files[strings.TrimSpace(fields[1])] = fields[0]
+	}
+	return l, s.Err()
+}
+
+//Allowed reports whether filename is allowed with its current content
+func (l *AllowList) Allowed(filename string) (bool, error) {
+	abs, sum, err := hashFile(filename)
+	if err != nil {
+		return false, err
+	}
+	return l.files[abs] == sum, nil
+}
+
+//Check returns ErrNotAllowed unless filename is allowed
+func (l *AllowList) Check(filename string) error {
+	ok, err := l.Allowed(filename)
+	if err == nil && !ok {
+		err = fmt.Errorf("%s is %w", filename, ErrNotAllowed)
+	}
+	return err
+}
+
+//Allow adds filename with its current content and saves the list
+func (l *AllowList) Allow(filename string) error {
+	abs, sum, err := hashFile(filename)
+	if err != nil {
+		return err
+	}
+	l.files[abs] = sum
+	return l.save()
+}
+
+//Deny removes filename and saves the list
+func (l *AllowList) Deny(filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	delete(l.files, abs)
+	return l.save()
+}
+
+func (l *AllowList) save() error {
+	names := make([]string, 0, len(l.files))
+	for name := range l.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s %s\n", l.files[name], name)
+	}
+	return ioutil.WriteFile(l.File, []byte(b.String()), 0600)
+}
+
+//hashFile returns the absolute path of filename and the sha256 of its
+//content
+func hashFile(filename string) (string, string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	return abs, hex.EncodeToString(sum[:]), nil
+}
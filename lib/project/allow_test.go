@@ -0,0 +1,50 @@
+package project
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	listFile := filepath.Join(dir, "allowed")
+	local := filepath.Join(dir, "my repo", LocalConfigFile)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(local), 0755))
+	assert.NoError(t, ioutil.WriteFile(local, []byte("env: {FOO: bar}\n"), 0644))
+
+	l, err := ReadAllowList(listFile)
+	assert.NoError(t, err)
+	err = l.Check(local)
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+
+	assert.NoError(t, l.Allow(local))
+	l, err = ReadAllowList(listFile)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Check(local))
+
+	// a changed file has to be allowed again
+	assert.NoError(t, ioutil.WriteFile(local, []byte("env: {FOO: baz}\n"), 0644))
+	ok, err := l.Allowed(local)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, l.Allow(local))
+	assert.NoError(t, l.Deny(local))
+	l, err = ReadAllowList(listFile)
+	assert.NoError(t, err)
+	ok, err = l.Allowed(local)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, ioutil.WriteFile(listFile, []byte("garbage\n"), 0600))
+	_, err = ReadAllowList(listFile)
+	assert.Error(t, err)
+}
@@ -17,44 +17,118 @@ limitations under the License.
 package project
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	"github.com/kmpm/gopr/lib/secret"
+	"gopkg.in/yaml.v3"
 )
 
 // Config contains project specific config
 type Config struct {
-	Go111Module bool              `yaml:"go111module"`
+	Version     int               `yaml:"version,omitempty"`
+	Extends     []string          `yaml:"extends,omitempty"`
+	Go111Module string            `yaml:"go111module,omitempty"`
 	GoPrivate   string            `yaml:"goprivate"`
+	GoVersion   string            `yaml:"goversion,omitempty"`
+	GoProxy     string            `yaml:"goproxy,omitempty"`
+	GoNoSumDB   string            `yaml:"gonosumdb,omitempty"`
+	GoNoProxy   string            `yaml:"gonoproxy,omitempty"`
+	GoFlags     string            `yaml:"goflags,omitempty"`
+	GoOS        string            `yaml:"goos,omitempty"`
+	GoArch      string            `yaml:"goarch,omitempty"`
+	CgoEnabled  string            `yaml:"cgo_enabled,omitempty"`
+	GoWork      string            `yaml:"gowork,omitempty"`
+	GoToolchain string            `yaml:"gotoolchain,omitempty"`
 	Env         map[string]string `yaml:"env,flow"`
+	// Path changes PATH and PathLists other lists like LD_LIBRARY_PATH
+	Path      PathList            `yaml:"path,omitempty"`
+	PathLists map[string]PathList `yaml:"pathlists,omitempty"`
+	Tools     []string            `yaml:"tools,omitempty"`
 }
 
-//ReadConfig creates a *Config from a yaml file
+//ReadConfig creates a *Config from a yaml file. Files with an older
+//version are migrated in memory, use Upgrade to update the file. Unknown
+//keys are reported with their line number.
 func ReadConfig(filename string) (*Config, error) {
-	if _, err := os.Stat(filename); err != nil {
-		return nil, err
-	}
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	doc, _, err := load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if err := checkConfigKeys(doc, configKeys); err != nil {
+		return nil, fmt.Errorf("%s:%w", filename, err)
+	}
 	c := &Config{}
-	err = yaml.Unmarshal(data, c)
-	// fmt.Printf("Project Config: %+v\n", c)
-	return c, err
+	if err := doc.Decode(c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return c, nil
 }
 
 //WriteConfig to save config to yaml file
 func WriteConfig(c *Config, filename string) error {
-	// err := touch(filename)
-	// if err != nil {
-	// 	return err
-	// }
-
-	out, err := yaml.Marshal(c)
+	c.Version = CurrentVersion
+	out, err := encode(c)
 	if err != nil {
 		return err
 	}
 	return ioutil.WriteFile(filename, out, 0644)
 }
+
+//encode marshals v the way the files are formatted
+func encode(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+//replacePath replaces the directory old with new in s. Only whole path
+//elements match, old has to be at the start of s or after a list
+//separator, a space or a =, and be followed by the end of s, a path or
+//list separator or a space. /a/b is not replaced in /a/bc or /x/a/b. The
+//path of a secret://file/ reference is replaced too.
+func replacePath(s, old, new string) string {
+	if old == "" {
+		return s
+	}
+	if ref := secret.Scheme + "file/"; strings.HasPrefix(s, ref) {
+		return ref + replacePath(s[len(ref):], old, new)
+	}
+	list := string(os.PathListSeparator)
+	var b strings.Builder
+	done := 0
+	for i := 0; ; {
+		j := strings.Index(s[i:], old)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(old)
+		if (start == 0 || strings.ContainsRune(" ="+list, rune(s[start-1]))) &&
+			(end == len(s) || strings.ContainsRune(`/\ `+list, rune(s[end]))) {
+			b.WriteString(s[done:start])
+			b.WriteString(new)
+			done, i = end, end
+		} else {
+			i = start + 1
+		}
+	}
+	b.WriteString(s[done:])
+	return b.String()
+}
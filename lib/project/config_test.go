@@ -0,0 +1,79 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewritePaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "project.yaml")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`version: 3
+goprivate: ""
+env:
+  DATA: /root/old # the data
+  CONFIG: /root/old/etc/app.conf
+  LIST: /usr/lib:/root/old/lib:/root/old
+  FLAGS: -x --dir=/root/old
+  OTHER: /root/older/x
+  NESTED: /x/root/old
+  NAME: old
+  TOKEN: secret://file//root/old/token
+  REMOTE: secret://cmd/cat /root/old/token
+path:
+  prepend: [/root/old/bin, /usr/bin]
+pathlists:
+  LD_LIBRARY_PATH:
+    remove: [/root/old/lib]
+`), 0644))
+
+	d, err := ReadConfigDocument(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, d.RewritePaths("/root/old", "/root/new"))
+	assert.NoError(t, d.Write(filename))
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, `version: 3
+goprivate: ""
+env:
+  DATA: /root/new # the data
+  CONFIG: /root/new/etc/app.conf
+  LIST: /usr/lib:/root/new/lib:/root/new
+  FLAGS: -x --dir=/root/new
+  OTHER: /root/older/x
+  NESTED: /x/root/old
+  NAME: old
+  TOKEN: secret://file//root/new/token
+  REMOTE: secret://cmd/cat /root/new/token
+path:
+  prepend: [/root/new/bin, /usr/bin]
+pathlists:
+  LD_LIBRARY_PATH:
+    remove: [/root/new/lib]
+`, string(data))
+
+	d, err = ReadConfigDocument(filepath.Join(dir, "missing.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, d.RewritePaths("/root/old", "/root/new"))
+}
+
+func TestReplacePath(t *testing.T) {
+	for s, want := range map[string]string{
+		"/root/old":                     "/root/new",
+		"/root/old/root/old":            "/root/new/root/old",
+		"/x/root/old":                   "/x/root/old",
+		"/root/olden":                   "/root/olden",
+		"a /root/old b=/root/old":       "a /root/new b=/root/new",
+		"secret://file//root/old/token": "secret://file//root/new/token",
+		"secret://file//x/root/old":     "secret://file//x/root/old",
+		"secret://file/~/root/old":      "secret://file/~/root/old",
+	} {
+		assert.Equal(t, want, replacePath(s, "/root/old", "/root/new"), s)
+	}
+}
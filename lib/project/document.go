@@ -0,0 +1,405 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/pathlist"
+	"github.com/kmpm/gopr/lib/shell"
+	"github.com/kmpm/gopr/lib/toolchain"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNotMapping - A key is used as a map but holds another kind of value
+	ErrNotMapping = errors.New("not a mapping")
+	// ErrInvalidValue - The value is not valid for the setting
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrReadOnly - The setting is managed by gopr
+	ErrReadOnly = errors.New("setting can not be changed")
+)
+
+//Document is a yaml file of settings kept as yaml nodes, so edits with
+//Set and Unset keep the comments and layout of the file. Keys are dotted
+//paths like env.GOFLAGS.
+type Document struct {
+	root *yaml.Node
+}
+
+//ReadDocument reads a yaml file. A file that does not exist gives an
+//empty document.
+func ReadDocument(filename string) (*Document, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file yaml.Node
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(file.Content) > 0 {
+		root = file.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d: %w", filename, root.Line, ErrNotMapping)
+	}
+	return &Document{root: root}, nil
+}
+
+//ReadConfigDocument reads a project.yaml as a Document, migrated to the
+//CurrentVersion
+func ReadConfigDocument(filename string) (*Document, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	root, _, err := load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &Document{root: root}, nil
+}
+
+//Get returns the value of key. Lists are joined with commas, directories
+//of path and pathlists like in PATH.
+func (d *Document) Get(key string) (string, bool) {
+	n := d.lookup(key)
+	if n == nil {
+		return "", false
+	}
+	if isPathKey(key) && n.Kind == yaml.SequenceNode {
+		dirs := make([]string, 0, len(n.Content))
+		for _, c := range n.Content {
+			dirs = append(dirs, nodeString(c))
+		}
+		return pathlist.Join(dirs), true
+	}
+	return nodeString(n), true
+}
+
+//Set gives key the value. Missing maps along the path are created.
+func (d *Document) Set(key string, value *yaml.Node) error {
+	parts := strings.Split(key, ".")
+	m := d.root
+	for _, p := range parts[:len(parts)-1] {
+		next := mapValue(m, p)
+		if next == nil {
+			// new maps are written like WriteConfig does
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}
+			appendMapValue(m, p, next)
+		}
+		if next.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: %w", p, ErrNotMapping)
+		}
+		m = next
+	}
+	last := parts[len(parts)-1]
+	if old := mapValue(m, last); old != nil {
+		value.HeadComment, value.LineComment = old.HeadComment, old.LineComment
+		setMapValue(m, last, value)
+		return nil
+	}
+	appendMapValue(m, last, value)
+	return nil
+}
+
+//Unset removes key and reports whether it was there
+func (d *Document) Unset(key string) bool {
+	parts := strings.Split(key, ".")
+	m := d.root
+	if len(parts) > 1 {
+		m = d.lookup(strings.Join(parts[:len(parts)-1], "."))
+	}
+	if m == nil || m.Kind != yaml.MappingNode {
+		return false
+	}
+	return deleteMapValue(m, parts[len(parts)-1])
+}
+
+//Keys returns the dotted paths of every value that is not a map, in the
+//order they are in the file
+func (d *Document) Keys() []string {
+	var keys []string
+	var walk func(prefix string, m *yaml.Node)
+	walk = func(prefix string, m *yaml.Node) {
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			k, v := prefix+m.Content[i].Value, m.Content[i+1]
+			if v.Kind == yaml.MappingNode {
+				walk(k+".", v)
+			} else {
+				keys = append(keys, k)
+			}
+		}
+	}
+	walk("", d.root)
+	return keys
+}
+
+//Decode decodes the document into v
+func (d *Document) Decode(v interface{}) error {
+	return d.root.Decode(v)
+}
+
+//Config decodes the document as a project.yaml, unknown keys are errors
+func (d *Document) Config() (*Config, error) {
+	if err := checkConfigKeys(d.root, configKeys); err != nil {
+		return nil, fmt.Errorf("line %w", err)
+	}
+	c := &Config{}
+	if err := d.root.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+//Write saves the document to filename
+func (d *Document) Write(filename string) error {
+	out, err := encode(d.root)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, out, 0644)
+}
+
+//RewritePaths replaces the directory old with new in the env values and
+//the directories of path and pathlists, and returns how many values
+//changed. Only whole path elements match, see replacePath.
+func (d *Document) RewritePaths(old, new string) int {
+	changed := 0
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			if r := replacePath(n.Value, old, new); r != n.Value {
+				n.Value = r
+				changed++
+			}
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		case yaml.SequenceNode:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		}
+	}
+	for _, key := range []string{"env", "path", "pathlists"} {
+		if n := mapValue(d.root, key); n != nil {
+			walk(n)
+		}
+	}
+	return changed
+}
+
+//RenameExtends replaces the name old with new in extends and returns how
+//many entries changed
+func (d *Document) RenameExtends(old, new string) int {
+	changed := 0
+	if n := mapValue(d.root, "extends"); n != nil && n.Kind == yaml.SequenceNode {
+		for _, c := range n.Content {
+			if c.Kind == yaml.ScalarNode && c.Value == old {
+				c.Value = new
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+func (d *Document) lookup(key string) *yaml.Node {
+	n := d.root
+	for _, p := range strings.Split(key, ".") {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		if n = mapValue(n, p); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+func nodeString(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(n.Content))
+		for _, c := range n.Content {
+			items = append(items, nodeString(c))
+		}
+		return strings.Join(items, ",")
+	case yaml.MappingNode:
+		out, _ := encode(n)
+		return strings.TrimSpace(string(out))
+	case yaml.AliasNode:
+		return nodeString(n.Alias)
+	}
+	return n.Value
+}
+
+//StringNode returns a yaml string value. Words that are booleans in
+//YAML 1.1, like on and off, are quoted for older parsers.
+func StringNode(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	switch strings.ToLower(s) {
+	case "on", "off", "yes", "no", "y", "n":
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
+
+//ListNode returns a yaml list of strings
+func ListNode(items []string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, s := range items {
+		n.Content = append(n.Content, StringNode(s))
+	}
+	return n
+}
+
+//ConfigValue validates value for the project.yaml setting key and returns
+//it as a yaml node
+func ConfigValue(key, value string) (*yaml.Node, error) {
+	if err := CheckConfigKey(key); err != nil {
+		return nil, err
+	}
+	switch {
+	case key == "goprivate":
+		patterns, err := splitList(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrInvalidValue, key, err)
+		}
+		return StringNode(strings.Join(patterns, ",")), nil
+	case key == "goversion":
+		if !toolchain.Valid(value) {
+			return nil, fmt.Errorf("%w for %s: %q is not a go version", ErrInvalidValue, key, value)
+		}
+		return StringNode(toolchain.Normalize(value)), nil
+	case key == "tools", key == "extends":
+		items, err := splitList(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrInvalidValue, key, err)
+		}
+		if key == "extends" {
+			if err := checkExtends(items); err != nil {
+				return nil, err
+			}
+		}
+		return ListNode(items), nil
+	case isGoEnvKey(key):
+		s, _ := findGoEnvSetting(key)
+		if err := s.check(value); value != "" && err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrInvalidValue, key, err)
+		}
+		return StringNode(value), nil
+	case strings.HasPrefix(key, "env."):
+		if name := strings.TrimPrefix(key, "env."); !shell.ValidName(name) {
+			return nil, fmt.Errorf("%w: %q is not a variable name", ErrInvalidValue, name)
+		}
+		return StringNode(value), nil
+	case isPathKey(key):
+		dirs := pathlist.Split(value)
+		for _, d := range dirs {
+			if err := checkPathDir(d); err != nil {
+				return nil, fmt.Errorf("%w for %s: %v", ErrInvalidValue, key, err)
+			}
+		}
+		return ListNode(dirs), nil
+	case key == "path", strings.HasPrefix(key, "pathlists"):
+		return nil, fmt.Errorf("%w for %s, set the directories with path.prepend, path.append, path.remove or pathlists.NAME.prepend and so on", ErrInvalidValue, key)
+	}
+	return nil, fmt.Errorf("%w for %s, set the variables with env.NAME", ErrInvalidValue, key)
+}
+
+//isPathKey reports whether key is a list of directories in path or
+//pathlists
+func isPathKey(key string) bool {
+	parts := strings.Split(key, ".")
+	switch {
+	case len(parts) == 2 && parts[0] == "path":
+	case len(parts) == 3 && parts[0] == "pathlists":
+	default:
+		return false
+	}
+	return contains(pathDirectives, parts[len(parts)-1])
+}
+
+//ConfigKeys returns the settings in project.yaml that can be changed
+func ConfigKeys() []string {
+	keys := []string{}
+	for _, k := range configKeys {
+		if k == "path" {
+			for _, d := range pathDirectives {
+				keys = append(keys, "path."+d)
+			}
+		} else if CheckConfigKey(k) == nil {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+//CheckConfigKey returns an error if key can not be changed in project.yaml
+func CheckConfigKey(key string) error {
+	switch {
+	case key == "version":
+		return fmt.Errorf("%s: %w", key, ErrReadOnly)
+	case key == "env", strings.HasPrefix(key, "env."):
+		return nil
+	case strings.HasPrefix(key, "pathlists."):
+		parts := strings.Split(key, ".")
+		if err := checkPathListName(parts[1]); err != nil || len(parts) == 2 || isPathKey(key) {
+			return err
+		}
+	case isPathKey(key), key == "path", key == "pathlists":
+		return nil
+	}
+	for _, k := range configKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnknownKey, key)
+}
+
+//splitList splits a comma separated list, an empty string is no items
+func splitList(value string) ([]string, error) {
+	items := []string{}
+	if strings.TrimSpace(value) == "" {
+		return items, nil
+	}
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" || strings.ContainsAny(s, " \t") {
+			return nil, fmt.Errorf("invalid item %q", s)
+		}
+		items = append(items, s)
+	}
+	return items, nil
+}
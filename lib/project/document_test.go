@@ -0,0 +1,136 @@
+package project
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDocumentEdit(t *testing.T) {
+	filename, cleanup := copyFixture(t, "v1.yaml")
+	defer cleanup()
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`# project settings
+version: 1
+go111module: true # modules
+goprivate: example.com
+env: {A: "1"}
+`), 0644))
+
+	d, err := ReadConfigDocument(filename)
+	assert.NoError(t, err)
+	v, ok := d.Get("env.A")
+	assert.True(t, ok)
+	assert.Equal(t, "1", v)
+	_, ok = d.Get("env.B")
+	assert.False(t, ok)
+
+	for key, value := range map[string]string{
+		"go111module": "off",
+		"env.B":       "two words",
+		"tools":       "a@latest, b@v1.0.0",
+	} {
+		n, err := ConfigValue(key, value)
+		assert.NoError(t, err, key)
+		assert.NoError(t, d.Set(key, n), key)
+	}
+	assert.True(t, d.Unset("goprivate"))
+	assert.False(t, d.Unset("goprivate"))
+	assert.Equal(t, []string{"version", "go111module", "env.A", "env.B", "tools"}, d.Keys())
+	v, _ = d.Get("go111module")
+	assert.Equal(t, "off", v)
+
+	assert.NoError(t, d.Write(filename))
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, `# project settings
+version: 3
+go111module: "off" # modules
+env: {A: "1", B: two words}
+tools:
+  - a@latest
+  - b@v1.0.0
+`, string(data))
+
+	c, err := ReadConfig(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a@latest", "b@v1.0.0"}, c.Tools)
+}
+
+func TestDocumentNew(t *testing.T) {
+	d, err := ReadConfigDocument(filepath.Join("testdata", "missing.yaml"))
+	assert.NoError(t, err)
+	n, _ := ConfigValue("env.X", "y")
+	assert.NoError(t, d.Set("env.X", n))
+	c, err := d.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{Version: CurrentVersion, Env: map[string]string{"X": "y"}}, c)
+
+	n, _ = ConfigValue("goprivate", "x")
+	assert.NoError(t, d.Set("goprivate", n))
+	assert.True(t, errors.Is(d.Set("goprivate.deep", n), ErrNotMapping))
+}
+
+func TestRenameExtends(t *testing.T) {
+	filename, cleanup := copyFixture(t, "v1.yaml")
+	defer cleanup()
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`version: 3
+extends: [base, old] # parents
+env: {A: old}
+`), 0644))
+
+	d, err := ReadConfigDocument(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.RenameExtends("old", "new"))
+	assert.Equal(t, 0, d.RenameExtends("missing", "new"))
+	assert.NoError(t, d.Write(filename))
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, `version: 3
+extends: [base, new] # parents
+env: {A: old}
+`, string(data))
+}
+
+func TestConfigValue(t *testing.T) {
+	n, err := ConfigValue("goversion", "1.14")
+	assert.NoError(t, err)
+	assert.Equal(t, "go1.14", n.Value)
+
+	for key, value := range map[string]string{
+		"go111module":           "maybe",
+		"goflags":               "-v mod=mod",
+		"goos":                  "amiga",
+		"goversion":             "latest",
+		"goprivate":             "a.com,,b.com",
+		"env.1X":                "x",
+		"env":                   "x",
+		"path":                  "/a",
+		"path.prepend":          "${A",
+		"pathlists.PATH.append": "/a",
+		"pathlists.1X.append":   "/a",
+	} {
+		_, err := ConfigValue(key, value)
+		assert.True(t, errors.Is(err, ErrInvalidValue), key)
+	}
+	_, err = ConfigValue("version", "2")
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	_, err = ConfigValue("goprivat", "x")
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	_, err = ConfigValue("path.first", "/a")
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+
+	sep := string(os.PathListSeparator)
+	n, err = ConfigValue("pathlists.LD_LIBRARY_PATH.append", "/a"+sep+sep+"/b")
+	assert.NoError(t, err)
+	assert.Equal(t, ListNode([]string{"/a", "/b"}), n)
+
+	d := &Document{root: &yaml.Node{Kind: yaml.MappingNode}}
+	assert.NoError(t, d.Set("path.prepend", n))
+	v, _ := d.Get("path.prepend")
+	assert.Equal(t, "/a"+sep+"/b", v)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrExtendsCycle - Configs extend each other in a loop
+	ErrExtendsCycle = errors.New("extends cycle")
+)
+
+//Layer is one config file with only the settings in that file
+type Layer struct {
+	Name   string
+	File   string
+	Config *Config
+}
+
+//ReadLayers reads the config called name and, depth first, the configs
+//it extends. find returns the file of a name. The layers are in the order
+//they apply, the extended configs first and name last. A config extended
+//more than once is only included the first time. An extended config file
+//that does not exist is an empty layer.
+func ReadLayers(name string, find func(name string) (string, error)) ([]Layer, error) {
+	r := &layerReader{find: find, seen: map[string]bool{}, active: map[string]bool{}}
+	if err := r.read(name, nil); err != nil {
+		return nil, err
+	}
+	return r.layers, nil
+}
+
+//Flatten merges layers into one config. Settings in later layers take
+//precedence, see Overlay.
+func Flatten(layers []Layer) *Config {
+	c := &Config{Env: map[string]string{}}
+	for _, l := range layers {
+		c.Overlay(l.Config)
+	}
+	return c
+}
+
+//layerReader is the state of one call to ReadLayers
+type layerReader struct {
+	find   func(string) (string, error)
+	layers []Layer
+	seen   map[string]bool
+	active map[string]bool
+}
+
+//read adds the layers of name, chain is the names that extended it
+func (r *layerReader) read(name string, chain []string) error {
+	file, err := r.find(name)
+	if err != nil {
+		return err
+	}
+	file = filepath.Clean(file)
+	chain = append(chain, name)
+	if r.active[file] {
+		return fmt.Errorf("%w: %s", ErrExtendsCycle, strings.Join(chain, " -> "))
+	}
+	if r.seen[file] {
+		return nil
+	}
+	c, err := ReadConfig(file)
+	if os.IsNotExist(err) && len(chain) > 1 {
+		// an extended project without a config sets nothing
+		c, err = &Config{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	r.active[file] = true
+	for _, parent := range c.Extends {
+		if err := r.read(parent, chain); err != nil {
+			if len(chain) == 1 {
+				return fmt.Errorf("extends %s: %w", parent, err)
+			}
+			return err
+		}
+	}
+	delete(r.active, file)
+	r.seen[file] = true
+	r.layers = append(r.layers, Layer{Name: name, File: file, Config: c})
+	return nil
+}
+
+//checkExtends returns an error for names that can not be extended
+func checkExtends(names []string) error {
+	for _, name := range names {
+		if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("%w for extends: %q is not a project or profile name", ErrInvalidValue, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package project
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//writeConfigs writes each config to dir/<name>.yaml and returns a find
+//function for them
+func writeConfigs(t *testing.T, dir string, configs map[string]string) func(string) (string, error) {
+	for name, data := range configs {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(data), 0644))
+	}
+	return func(name string) (string, error) {
+		if _, ok := configs[name]; !ok {
+			return "", errors.New(name + " not found")
+		}
+		return filepath.Join(dir, name+".yaml"), nil
+	}
+}
+
+func TestReadLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extends")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	find := writeConfigs(t, dir, map[string]string{
+		"base": "version: 3\ngoprivate: base.example.com\ngoproxy: https://proxy.example.com\n" +
+			"env: {A: base, B: base}\ntools: [a@latest]\npath: {prepend: [/base]}\n",
+		"corp":  "version: 3\nextends: [base]\ngoprivate: corp.example.com\nenv: {B: corp, C: corp}\ntools: [b@latest, a@latest]\n",
+		"other": "version: 3\nextends: [base]\nenv: {D: other}\n",
+		"app":   "version: 3\nextends: [corp, other]\nenv: {C: app}\ntools: [c@latest]\npath: {prepend: [/app]}\n",
+	})
+	layers, err := ReadLayers("app", find)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, l := range layers {
+		names = append(names, l.Name)
+	}
+	// base is only included once
+	assert.Equal(t, []string{"base", "corp", "other", "app"}, names)
+	assert.Equal(t, filepath.Join(dir, "corp.yaml"), layers[1].File)
+	assert.Equal(t, map[string]string{"B": "corp", "C": "corp"}, layers[1].Config.Env)
+
+	c := Flatten(layers)
+	assert.Equal(t, "corp.example.com", c.GoPrivate)
+	assert.Equal(t, "https://proxy.example.com", c.GoProxy)
+	assert.Equal(t, map[string]string{"A": "base", "B": "corp", "C": "app", "D": "other"}, c.Env)
+	assert.Equal(t, []string{"a@latest", "b@latest", "c@latest"}, c.Tools)
+	assert.Equal(t, []string{"/app", "/base"}, c.Path.Prepend)
+}
+
+func TestReadLayersErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extends")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	find := writeConfigs(t, dir, map[string]string{
+		"a":    "extends: [b]\n",
+		"b":    "extends: [c]\n",
+		"c":    "extends: [a]\n",
+		"self": "extends: [self]\n",
+		"lost": "extends: [missing]\n",
+	})
+	_, err = ReadLayers("a", find)
+	assert.True(t, errors.Is(err, ErrExtendsCycle))
+	assert.EqualError(t, err, "extends b: extends cycle: a -> b -> c -> a")
+
+	_, err = ReadLayers("self", find)
+	assert.True(t, errors.Is(err, ErrExtendsCycle))
+
+	_, err = ReadLayers("lost", find)
+	assert.EqualError(t, err, "extends missing: missing not found")
+
+	_, err = ConfigValue("extends", "base,../x")
+	assert.True(t, errors.Is(err, ErrInvalidValue))
+}
+
+func TestReadLayersMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extends")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configs := writeConfigs(t, dir, map[string]string{
+		"app": "version: 3\nextends: [bare]\nenv: {A: app}\n",
+	})
+	// bare is a project without a config file
+	find := func(name string) (string, error) {
+		if name == "bare" {
+			return filepath.Join(dir, "bare.yaml"), nil
+		}
+		return configs(name)
+	}
+	layers, err := ReadLayers("app", find)
+	assert.NoError(t, err)
+	assert.Len(t, layers, 2)
+	assert.Equal(t, "bare", layers[0].Name)
+	assert.Equal(t, &Config{}, layers[0].Config)
+	assert.Equal(t, map[string]string{"A": "app"}, Flatten(layers).Env)
+
+	_, err = ReadLayers("bare", find)
+	assert.True(t, os.IsNotExist(err))
+}
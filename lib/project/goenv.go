@@ -0,0 +1,206 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/secret"
+)
+
+//goEnvSetting is a go environment variable that has its own key in
+//project.yaml
+type goEnvSetting struct {
+	// Key in project.yaml
+	Key string
+	// Name of the environment variable
+	Name string
+	// field returns the Config field holding the value
+	field func(c *Config) *string
+	// check validates a value that is not empty
+	check func(value string) error
+}
+
+var (
+	goEnvSettings = []goEnvSetting{
+		{"go111module", "GO111MODULE", func(c *Config) *string { return &c.Go111Module }, oneOf("on", "off", "auto")},
+		{"goproxy", "GOPROXY", func(c *Config) *string { return &c.GoProxy }, checkGoProxy},
+		{"gonosumdb", "GONOSUMDB", func(c *Config) *string { return &c.GoNoSumDB }, checkPatterns},
+		{"gonoproxy", "GONOPROXY", func(c *Config) *string { return &c.GoNoProxy }, checkPatterns},
+		{"goflags", "GOFLAGS", func(c *Config) *string { return &c.GoFlags }, checkGoFlags},
+		{"goos", "GOOS", func(c *Config) *string { return &c.GoOS }, oneOf(knownOS...)},
+		{"goarch", "GOARCH", func(c *Config) *string { return &c.GoArch }, oneOf(knownArch...)},
+		{"cgo_enabled", "CGO_ENABLED", func(c *Config) *string { return &c.CgoEnabled }, oneOf("0", "1")},
+		{"gowork", "GOWORK", func(c *Config) *string { return &c.GoWork }, checkGoWork},
+		{"gotoolchain", "GOTOOLCHAIN", func(c *Config) *string { return &c.GoToolchain }, checkGoToolchain},
+	}
+
+	// knownOS and knownArch are the values the go command knows of, from
+	// go/build/syslist.go
+	knownOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos"}
+	knownArch = []string{"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64",
+		"mips", "mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le",
+		"riscv", "riscv64", "s390", "s390x", "sparc", "sparc64", "wasm"}
+
+	goToolchainRe = regexp.MustCompile(`^(auto|path|(local|go1(\.[0-9]+){0,2}((rc|beta)[0-9]+)?)(\+(auto|path))?)$`)
+)
+
+//GoEnv returns the go environment variables the configuration sets, in a
+//fixed order
+func (c *Config) GoEnv() [][2]string {
+	vars := [][2]string{}
+	for _, s := range goEnvSettings {
+		if v := *s.field(c); v != "" {
+			vars = append(vars, [2]string{s.Name, v})
+		}
+	}
+	return vars
+}
+
+//Validate checks the values of the go environment variables and goprivate
+//the way the go command would
+func (c *Config) Validate() error {
+	if err := checkPatterns(c.GoPrivate); c.GoPrivate != "" && err != nil {
+		return fmt.Errorf("%w for goprivate: %v", ErrInvalidValue, err)
+	}
+	for _, s := range goEnvSettings {
+		if v := *s.field(c); v != "" {
+			if err := s.check(v); err != nil {
+				return fmt.Errorf("%w for %s: %v", ErrInvalidValue, s.Key, err)
+			}
+		}
+	}
+	// references to other env values can be checked without the host
+	if _, err := (&Expander{}).Expand(c.Env); err != nil {
+		return fmt.Errorf("%w for %v", ErrInvalidValue, err)
+	}
+	for k, v := range c.Env {
+		if _, _, err := secret.Parse(v); secret.IsRef(v) && err != nil {
+			return fmt.Errorf("%w for env %s: %v", ErrInvalidValue, k, err)
+		}
+	}
+	if err := checkExtends(c.Extends); err != nil {
+		return err
+	}
+	return c.checkPaths()
+}
+
+//CheckGoEnv validates value for the go environment setting key, like
+//go111module, the same way Validate does
+func CheckGoEnv(key, value string) error {
+	s, ok := findGoEnvSetting(key)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKey, key)
+	}
+	if err := s.check(value); err != nil {
+		return fmt.Errorf("%w for %s: %v", ErrInvalidValue, key, err)
+	}
+	return nil
+}
+
+func findGoEnvSetting(key string) (goEnvSetting, bool) {
+	for _, s := range goEnvSettings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return goEnvSetting{}, false
+}
+
+func isGoEnvKey(key string) bool {
+	_, ok := findGoEnvSetting(key)
+	return ok
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, a := range values {
+			if v == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be one of %s", v, strings.Join(values, ", "))
+	}
+}
+
+//checkGoProxy accepts a list of proxy urls, direct and off separated by
+//commas or pipes. Like the go command https:// is implied for entries
+//without a scheme that look like a host, such as proxy.golang.org.
+func checkGoProxy(v string) error {
+	for _, p := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == '|' }) {
+		if p == "direct" || p == "off" {
+			continue
+		}
+		proxy := p
+		if strings.ContainsAny(proxy, ".:/") && !strings.Contains(proxy, ":/") && !filepath.IsAbs(proxy) && !path.IsAbs(proxy) {
+			proxy = "https://" + proxy
+		}
+		u, err := url.Parse(proxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+			return fmt.Errorf("%q is not a proxy url, direct or off", p)
+		}
+	}
+	return nil
+}
+
+//checkPatterns accepts a comma separated list of module path globs
+func checkPatterns(v string) error {
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.ContainsAny(p, " \t") {
+			return fmt.Errorf("%q contains a space", p)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+//checkGoFlags accepts space separated flags
+func checkGoFlags(v string) error {
+	for _, f := range strings.Fields(v) {
+		if !strings.HasPrefix(f, "-") {
+			return fmt.Errorf("%q is not a flag, entries must start with -", f)
+		}
+	}
+	return nil
+}
+
+func checkGoWork(v string) error {
+	if v != "off" && !filepath.IsAbs(v) {
+		return errors.New("must be off or an absolute path to a go.work file")
+	}
+	return nil
+}
+
+func checkGoToolchain(v string) error {
+	if !goToolchainRe.MatchString(v) {
+		return fmt.Errorf("%q must be local, auto, path or a go version, optionally followed by +auto or +path", v)
+	}
+	return nil
+}
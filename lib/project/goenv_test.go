@@ -0,0 +1,60 @@
+package project
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoEnvChecks(t *testing.T) {
+	valid := map[string][]string{
+		"goproxy":     {"direct", "off", "https://proxy.golang.org,direct", "https://a.example.com|file:///srv/proxy", "proxy.golang.org,direct", "goproxy.example.com:8080/go"},
+		"gonosumdb":   {"example.com", "*.corp.example.com,example.org/private"},
+		"goflags":     {"-mod=mod", "-mod=mod -trimpath"},
+		"goos":        {"linux", "windows"},
+		"goarch":      {"amd64", "arm64"},
+		"cgo_enabled": {"0", "1"},
+		"gowork":      {"off"},
+		"gotoolchain": {"local", "auto", "go1.21.0", "go1.21.0+auto", "local+path", "go1.22rc1"},
+	}
+	invalid := map[string][]string{
+		"goproxy":     {"proxy", "ftp://example.com"},
+		"gonosumdb":   {"example.com/[", "example .com"},
+		"goflags":     {"mod=mod"},
+		"goos":        {"amiga"},
+		"goarch":      {"x86"},
+		"cgo_enabled": {"true"},
+		"gowork":      {"go.work"},
+		"gotoolchain": {"latest", "1.21.0", "go1.21.0+local"},
+	}
+	for key, values := range valid {
+		s, ok := findGoEnvSetting(key)
+		assert.True(t, ok, key)
+		for _, v := range values {
+			assert.NoError(t, s.check(v), "%s=%s", key, v)
+		}
+	}
+	for key, values := range invalid {
+		s, _ := findGoEnvSetting(key)
+		for _, v := range values {
+			assert.Error(t, s.check(v), "%s=%s", key, v)
+		}
+	}
+}
+
+func TestCheckGoEnv(t *testing.T) {
+	assert.NoError(t, CheckGoEnv("go111module", "auto"))
+	assert.True(t, errors.Is(CheckGoEnv("go111module", "banana"), ErrInvalidValue))
+	assert.True(t, errors.Is(CheckGoEnv("banana", "on"), ErrUnknownKey))
+}
+
+func TestGoEnv(t *testing.T) {
+	c := &Config{Go111Module: "auto", GoFlags: "-mod=mod", CgoEnabled: "0"}
+	assert.Equal(t, [][2]string{{"GO111MODULE", "auto"}, {"GOFLAGS", "-mod=mod"}, {"CGO_ENABLED", "0"}}, c.GoEnv())
+
+	c.Overlay(&Config{CgoEnabled: "1", GoOS: "windows"})
+	assert.Equal(t, "1", c.CgoEnabled)
+	assert.Equal(t, "windows", c.GoOS)
+	assert.Equal(t, "auto", c.Go111Module)
+}
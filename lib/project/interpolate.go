@@ -0,0 +1,193 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/secret"
+)
+
+var (
+	// ErrReferenceCycle - Env values refer to each other in a loop
+	ErrReferenceCycle = errors.New("reference cycle")
+	// ErrInvalidReference - A ${ is not closed or does not contain a name
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+//Expander resolves references like ${NAME} or $NAME in env values. A name
+//is looked up first among the env values themselves, then in Builtins and
+//last with Lookup, normally os.LookupEnv. Names not found anywhere expand
+//to an empty string. A value referring to its own name gets the value
+//from Builtins or Lookup, so PATH-like values can be extended. $$ is a
+//literal $ and a ~ at the start of a value is replaced with Home. Values
+//that refer to a secret are used as they are, nothing is expanded in them.
+type Expander struct {
+	Builtins map[string]string
+	Lookup   func(string) (string, bool)
+	Home     string
+	// Resolve, if set, gets each env value that refers to a secret and
+	// returns the value to use, like the looked up secret
+	Resolve func(string) (string, error)
+}
+
+//Expand returns a copy of env with all references resolved
+func (x *Expander) Expand(env map[string]string) (map[string]string, error) {
+	e := &expansion{Expander: x, env: env, done: make(map[string]string, len(env)), active: map[string]bool{}}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	// sorted so the same cycle is always reported the same way
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := e.resolve(k, nil); err != nil {
+			return nil, fmt.Errorf("env %s: %w", k, err)
+		}
+	}
+	return e.done, nil
+}
+
+//ExpandValue resolves the references in a single value. env holds values
+//already resolved by Expand, they are looked up before Builtins.
+func (x *Expander) ExpandValue(s string, env map[string]string) (string, error) {
+	e := &expansion{Expander: x, env: env, done: env, active: map[string]bool{}}
+	return e.expand("", s, nil)
+}
+
+//expansion is the state of one call to Expand
+type expansion struct {
+	*Expander
+	env    map[string]string
+	done   map[string]string
+	active map[string]bool
+}
+
+//resolve expands the env value key, path is the chain of keys that led to
+//it and is used to report cycles
+func (e *expansion) resolve(key string, path []string) (string, error) {
+	if v, ok := e.done[key]; ok {
+		return v, nil
+	}
+	path = append(path, key)
+	if e.active[key] {
+		return "", fmt.Errorf("%w: %s", ErrReferenceCycle, strings.Join(path, " -> "))
+	}
+	var v string
+	var err error
+	switch raw := e.env[key]; {
+	case !secret.IsRef(raw):
+		e.active[key] = true
+		v, err = e.expand(key, raw, path)
+		delete(e.active, key)
+	case e.Resolve != nil:
+		// a reference is never expanded, the value of another variable
+		// could otherwise end up in the command of secret://cmd/
+		v, err = e.Resolve(raw)
+	default:
+		v = raw
+	}
+	if err != nil {
+		return "", err
+	}
+	e.done[key] = v
+	return v, nil
+}
+
+//expand resolves the references in the value of key
+func (e *expansion) expand(key, value string, path []string) (string, error) {
+	var b strings.Builder
+	if value == "~" || strings.HasPrefix(value, "~/") || strings.HasPrefix(value, `~\`) {
+		b.WriteString(e.Home)
+		value = value[1:]
+	}
+	for {
+		i := strings.IndexByte(value, '$')
+		if i < 0 || i == len(value)-1 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		b.WriteString(value[:i])
+		value = value[i+1:]
+
+		var name string
+		switch {
+		case value[0] == '$':
+			b.WriteByte('$')
+			value = value[1:]
+			continue
+		case value[0] == '{':
+			end := strings.IndexByte(value, '}')
+			if end < 0 {
+				return "", fmt.Errorf("%w: unclosed ${", ErrInvalidReference)
+			}
+			name = value[1:end]
+			if !isName(name) {
+				return "", fmt.Errorf("%w: ${%s}", ErrInvalidReference, name)
+			}
+			value = value[end+1:]
+		default:
+			n := nameLength(value)
+			if n == 0 {
+				// a lone $ is kept as it is
+				b.WriteByte('$')
+				continue
+			}
+			name, value = value[:n], value[n:]
+		}
+
+		v, err := e.lookup(key, name, path)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+}
+
+//lookup returns the value of name as referenced from the value of key
+func (e *expansion) lookup(key, name string, path []string) (string, error) {
+	if _, ok := e.env[name]; ok && name != key {
+		return e.resolve(name, path)
+	}
+	if v, ok := e.Builtins[name]; ok {
+		return v, nil
+	}
+	if e.Lookup != nil {
+		if v, ok := e.Lookup(name); ok {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+//nameLength returns the length of the variable name at the start of s
+func nameLength(s string) int {
+	for i, r := range s {
+		if r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+func isName(s string) bool {
+	return s != "" && nameLength(s) == len(s)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// LocalConfigFile is the name of the file that ties a source tree to a project
+	LocalConfigFile = ".gopr.yaml"
+)
+
+var (
+	// ErrNoLocalConfig - No LocalConfigFile was found in the directory or above
+	ErrNoLocalConfig = errors.New("no " + LocalConfigFile + " found")
+)
+
+// LocalConfig is kept in a source tree and names the project it belongs to.
+// Any project settings in it take precedence over the project.yaml.
+type LocalConfig struct {
+	Project string `yaml:"project"`
+	Config  `yaml:",inline"`
+	// File is where the configuration was read from
+	File string `yaml:"-"`
+}
+
+//FindLocalConfig looks for LocalConfigFile in dir and each of its parents
+//the way the go command looks for go.mod. Files listed in skip, like the
+//global configuration in the home directory, are passed over.
+func FindLocalConfig(dir string, skip ...string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	ignore := make(map[string]bool, len(skip))
+	for _, s := range skip {
+		if abs, err := filepath.Abs(s); err == nil {
+			ignore[abs] = true
+		}
+	}
+	for {
+		f := filepath.Join(dir, LocalConfigFile)
+		if info, err := os.Stat(f); err == nil && !info.IsDir() && !ignore[f] {
+			return f, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNoLocalConfig
+		}
+		dir = parent
+	}
+}
+
+//ReadLocalConfig reads a LocalConfigFile. If it does not name a project
+//the name of the directory it is in is used.
+func ReadLocalConfig(filename string) (*LocalConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	c := &LocalConfig{}
+	if len(doc.Content) > 0 {
+		if err := checkConfigKeys(doc.Content[0], localConfigKeys); err != nil {
+			return nil, fmt.Errorf("%s:%w", filename, err)
+		}
+		if err = doc.Content[0].Decode(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.File, err = filepath.Abs(filename); err != nil {
+		return nil, err
+	}
+	if c.Project == "" {
+		c.Project = filepath.Base(filepath.Dir(c.File))
+	}
+	return c, nil
+}
+
+//Overlay copies the settings that are set in o onto c
+func (c *Config) Overlay(o *Config) {
+	for _, s := range goEnvSettings {
+		if v := *s.field(o); v != "" {
+			*s.field(c) = v
+		}
+	}
+	if o.GoPrivate != "" {
+		c.GoPrivate = o.GoPrivate
+	}
+	if o.GoVersion != "" {
+		c.GoVersion = o.GoVersion
+	}
+	if len(o.Env) > 0 && c.Env == nil {
+		c.Env = make(map[string]string, len(o.Env))
+	}
+	for k, v := range o.Env {
+		c.Env[k] = v
+	}
+	c.Path.overlay(o.Path)
+	if len(o.PathLists) > 0 && c.PathLists == nil {
+		c.PathLists = make(map[string]PathList, len(o.PathLists))
+	}
+	for name, l := range o.PathLists {
+		merged := c.PathLists[name]
+		merged.overlay(l)
+		c.PathLists[name] = merged
+	}
+	for _, t := range o.Tools {
+		if !contains(c.Tools, t) {
+			c.Tools = append(c.Tools, t)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package project
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kmpm/gopr/lib/pathlist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindLocalConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "src", "repo")
+	deep := filepath.Join(repo, "cmd", "tool")
+	assert.NoError(t, os.MkdirAll(deep, 0755))
+
+	_, err = FindLocalConfig(deep)
+	assert.Equal(t, ErrNoLocalConfig, err)
+
+	global := filepath.Join(dir, LocalConfigFile)
+	assert.NoError(t, ioutil.WriteFile(global, []byte("root: /tmp\n"), 0644))
+	_, err = FindLocalConfig(deep, global)
+	assert.Equal(t, ErrNoLocalConfig, err)
+
+	local := filepath.Join(repo, LocalConfigFile)
+	assert.NoError(t, ioutil.WriteFile(local, []byte("env: {FOO: bar}\n"), 0644))
+	found, err := FindLocalConfig(deep, global)
+	assert.NoError(t, err)
+	assert.Equal(t, local, found)
+
+	lc, err := ReadLocalConfig(found)
+	assert.NoError(t, err)
+	assert.Equal(t, "repo", lc.Project)
+	assert.Equal(t, local, lc.File)
+	assert.Equal(t, map[string]string{"FOO": "bar"}, lc.Env)
+
+	assert.NoError(t, ioutil.WriteFile(local, []byte("project: other\ngoprivate: example.com\n"), 0644))
+	lc, err = ReadLocalConfig(found)
+	assert.NoError(t, err)
+	assert.Equal(t, "other", lc.Project)
+	assert.Equal(t, "example.com", lc.GoPrivate)
+
+	for _, data := range []string{"project: other\npath: {apend: [/x]}\n", "version: 3\n", "extends: [base]\n"} {
+		assert.NoError(t, ioutil.WriteFile(local, []byte(data), 0644))
+		_, err = ReadLocalConfig(found)
+		assert.True(t, errors.Is(err, ErrUnknownKey), data)
+	}
+
+	// the file is trusted by allowing it, references are kept as they are
+	assert.NoError(t, ioutil.WriteFile(local, []byte("env: {X: \"secret://cmd/touch /tmp/x\"}\n"), 0644))
+	lc, err = ReadLocalConfig(found)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret://cmd/touch /tmp/x", lc.Env["X"])
+}
+
+func TestOverlay(t *testing.T) {
+	c := &Config{GoPrivate: "example.com", GoVersion: "go1.14"}
+	c.Overlay(&Config{Go111Module: "on", GoVersion: "go1.15", Env: map[string]string{"A": "1"}})
+	assert.Equal(t, &Config{
+		Go111Module: "on",
+		GoPrivate:   "example.com",
+		GoVersion:   "go1.15",
+		Env:         map[string]string{"A": "1"},
+	}, c)
+}
+
+func TestOverlayTools(t *testing.T) {
+	c := &Config{Tools: []string{"a@latest"}}
+	c.Overlay(&Config{Tools: []string{"b@v1.0.0", "a@latest"}})
+	assert.Equal(t, []string{"a@latest", "b@v1.0.0"}, c.Tools)
+}
+
+func TestOverlayPaths(t *testing.T) {
+	c := &Config{
+		Path:      PathList{Prepend: []string{"/a"}},
+		PathLists: map[string]PathList{"LD_LIBRARY_PATH": {Append: []string{"/lib"}}},
+	}
+	c.Overlay(&Config{
+		Path: PathList{Prepend: []string{"/b", "/a"}, Remove: []string{"/c"}},
+		PathLists: map[string]PathList{
+			"LD_LIBRARY_PATH": {Append: []string{"/lib2"}},
+			"PKG_CONFIG_PATH": {Prepend: []string{"/pc"}},
+		},
+	})
+	assert.Equal(t, PathList{Prepend: []string{"/b", "/a"}, Remove: []string{"/c"}}, c.Path)
+	assert.Equal(t, map[string]PathList{
+		"LD_LIBRARY_PATH": {Append: []string{"/lib", "/lib2"}},
+		"PKG_CONFIG_PATH": {Prepend: []string{"/pc"}},
+	}, c.PathLists)
+}
+
+func TestOverlayPathOrder(t *testing.T) {
+	join := func(s ...string) string { return strings.Join(s, string(os.PathListSeparator)) }
+	layers := []PathList{
+		{Prepend: []string{"/opt/base/bin", "/shared"}, Append: []string{"/late"}, Remove: []string{"/gone"}},
+		{Prepend: []string{"/opt/child/bin", "/gone"}, Remove: []string{"/shared"}},
+		{Prepend: []string{"/late"}, Append: []string{"/opt/child/bin"}},
+	}
+	// applying the merged list is the same as applying each layer in turn
+	list := join("/usr/bin", "/gone", "/bin")
+	want := list
+	var merged PathList
+	for _, l := range layers {
+		want = pathlist.Edit{Prepend: l.Prepend, Append: l.Append, Remove: l.Remove}.Apply(want)
+		merged.overlay(l)
+	}
+	assert.Equal(t, join("/late", "/gone", "/opt/base/bin", "/usr/bin", "/bin", "/opt/child/bin"), want)
+	got := pathlist.Edit{Prepend: merged.Prepend, Append: merged.Append, Remove: merged.Remove}.Apply(list)
+	assert.Equal(t, want, got)
+
+	// the directories of a project come before the ones it extends
+	c := Flatten([]Layer{
+		{Config: &Config{Path: PathList{Prepend: []string{"/opt/base/bin"}}}},
+		{Config: &Config{Path: PathList{Prepend: []string{"/opt/child/bin"}}}},
+	})
+	assert.Equal(t, []string{"/opt/child/bin", "/opt/base/bin"}, c.Path.Prepend)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// CurrentVersion is the version of the project.yaml schema written by
+	// this version of gopr. Files without a version field are version 0.
+	CurrentVersion = 3
+)
+
+var (
+	// ErrNewerVersion - The file was written by a newer gopr
+	ErrNewerVersion = errors.New("configuration is from a newer version of gopr")
+	// ErrUnknownKey - The file contains a key that is not part of the schema
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrInvalidVersion - The version field is not a number
+	ErrInvalidVersion = errors.New("invalid version")
+
+	configKeys      = yamlKeys(reflect.TypeOf(Config{}))
+	// a local configuration is overlaid on the project, it has no version
+	// and does not extend anything
+	localConfigKeys = withoutKeys(yamlKeys(reflect.TypeOf(LocalConfig{})), "version", "extends")
+
+	// migrations upgrade a document one version at a time, the migration
+	// at index i upgrades from version i to i+1. They work on the yaml
+	// nodes so comments in the file are kept.
+	migrations = []func(doc *yaml.Node) error{
+		// 0 to 1 only introduces the version field
+		func(doc *yaml.Node) error { return nil },
+		// 1 to 2 turns the go111module bool into a string, true is on and
+		// false leaves it to the global setting
+		func(doc *yaml.Node) error {
+			v := mapValue(doc, "go111module")
+			if v == nil {
+				return nil
+			}
+			var on bool
+			if err := v.Decode(&on); err != nil {
+				return fmt.Errorf("line %d: go111module: %w", v.Line, err)
+			}
+			if !on {
+				deleteMapValue(doc, "go111module")
+				return nil
+			}
+			n := StringNode("on")
+			n.LineComment = v.LineComment
+			setMapValue(doc, "go111module", n)
+			return nil
+		},
+		// 2 to 3 escapes $ in env values since they are now interpolated.
+		// A ~ at the start of a value, as in ~ or ~/bin, now means the home
+		// directory. There is no way to write a literal leading ~ in
+		// version 3, so those values are left for the new meaning, which
+		// is almost always what was intended. A ~ anywhere else, or
+		// followed by a name as in ~user, stays literal.
+		func(doc *yaml.Node) error {
+			env := mapValue(doc, "env")
+			if env == nil || env.Kind != yaml.MappingNode {
+				return nil
+			}
+			for i := 1; i < len(env.Content); i += 2 {
+				if v := env.Content[i]; v.Kind == yaml.ScalarNode && strings.Contains(v.Value, "$") {
+					v.Value = strings.ReplaceAll(v.Value, "$", "$$")
+				}
+			}
+			return nil
+		},
+	}
+)
+
+//Upgrade migrates filename to CurrentVersion if it is older. The original
+//is kept with a .bak suffix. It returns the version the file had.
+func Upgrade(filename string) (int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	doc, from, err := load(data)
+	if err != nil {
+		return from, fmt.Errorf("%s: %w", filename, err)
+	}
+	if from == CurrentVersion {
+		return from, nil
+	}
+	out, err := encode(doc)
+	if err != nil {
+		return from, err
+	}
+	if err := ioutil.WriteFile(filename+".bak", data, 0644); err != nil {
+		return from, err
+	}
+	return from, ioutil.WriteFile(filename, out, 0644)
+}
+
+//load parses data and migrates it to CurrentVersion. It returns the
+//mapping node of the document and the version it had.
+func load(data []byte) (*yaml.Node, int, error) {
+	var file yaml.Node
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, 0, err
+	}
+	doc := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(file.Content) > 0 {
+		doc = file.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil, 0, fmt.Errorf("line %d: expected a mapping", doc.Line)
+	}
+
+	from := 0
+	if v := mapValue(doc, "version"); v != nil {
+		n, err := strconv.Atoi(v.Value)
+		if err != nil || n < 0 || v.Kind != yaml.ScalarNode {
+			return nil, 0, fmt.Errorf("line %d: %w %q", v.Line, ErrInvalidVersion, v.Value)
+		}
+		from = n
+	}
+	if from > CurrentVersion {
+		return nil, from, fmt.Errorf("%w, version %d is newer than %d", ErrNewerVersion, from, CurrentVersion)
+	}
+	for v := from; v < CurrentVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return nil, from, fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+	}
+	setMapValue(doc, "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(CurrentVersion)})
+	return doc, from, nil
+}
+
+//checkConfigKeys returns an error for the first key of a project.yaml,
+//or another file with the known keys, that is not known. The keys of path
+//and the lists of pathlists are checked too.
+func checkConfigKeys(doc *yaml.Node, known []string) error {
+	if err := checkKeys(doc, known, ""); err != nil {
+		return err
+	}
+	if n := mapValue(doc, "path"); n != nil && n.Kind == yaml.MappingNode {
+		if err := checkKeys(n, pathDirectives, "path."); err != nil {
+			return err
+		}
+	}
+	if n := mapValue(doc, "pathlists"); n != nil && n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if l := n.Content[i+1]; l.Kind == yaml.MappingNode {
+				if err := checkKeys(l, pathDirectives, "pathlists."+n.Content[i].Value+"."); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//checkKeys returns an error for the first key in doc that is not known,
+//prefix is put in front of the key in the error
+func checkKeys(doc *yaml.Node, known []string, prefix string) error {
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		k := doc.Content[i]
+		found := false
+		for _, name := range known {
+			if k.Value == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%d: %w %q", k.Line, ErrUnknownKey, prefix+k.Value)
+		}
+	}
+	return nil
+}
+
+//yamlKeys returns the keys of the struct t as named by the yaml tags,
+//including the ones of inlined structs
+func yamlKeys(t reflect.Type) []string {
+	keys := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		switch {
+		case tag[0] == "-":
+		case len(tag) > 1 && tag[1] == "inline":
+			keys = append(keys, yamlKeys(f.Type)...)
+		case tag[0] != "":
+			keys = append(keys, tag[0])
+		default:
+			keys = append(keys, strings.ToLower(f.Name))
+		}
+	}
+	return keys
+}
+
+//withoutKeys returns keys without the ones in drop
+func withoutKeys(keys []string, drop ...string) []string {
+	kept := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if !contains(drop, k) {
+			kept = append(kept, k)
+		}
+	}
+	return kept
+}
+
+//mapValue returns the value of key in the mapping node m or nil
+func mapValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+//setMapValue sets key in the mapping node m. New keys are put first,
+//below any comment at the top of the file.
+func setMapValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if len(m.Content) > 0 {
+		k.HeadComment, m.Content[0].HeadComment = m.Content[0].HeadComment, ""
+	}
+	m.Content = append([]*yaml.Node{k, value}, m.Content...)
+}
+
+//appendMapValue adds key last in the mapping node m
+func appendMapValue(m *yaml.Node, key string, value *yaml.Node) {
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	m.Content = append(m.Content, k, value)
+}
+
+//deleteMapValue removes key from the mapping node m and reports whether
+//it was there
+func deleteMapValue(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
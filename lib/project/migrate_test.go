@@ -0,0 +1,169 @@
+package project
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//copyFixture copies testdata/name into a temporary directory
+func copyFixture(t *testing.T, name string) (string, func()) {
+	dir, err := ioutil.TempDir("", "project")
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	assert.NoError(t, err)
+	filename := filepath.Join(dir, "project.yaml")
+	assert.NoError(t, ioutil.WriteFile(filename, data, 0644))
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+func TestReadConfigVersions(t *testing.T) {
+	want := map[string]*Config{
+		"v0.yaml": {
+			Version:     CurrentVersion,
+			Go111Module: "on",
+			GoPrivate:   "example.com",
+			Env:         map[string]string{"DOCKER_HOST": "ssh://build@example.com"},
+		},
+		"v1.yaml": {
+			Version:     CurrentVersion,
+			Go111Module: "on",
+			GoPrivate:   "example.com",
+			GoVersion:   "go1.14.1",
+			Env:         map[string]string{"DOCKER_HOST": "ssh://build@example.com"},
+			Tools:       []string{"golang.org/x/tools/gopls@latest"},
+		},
+		"v1-off.yaml": {
+			Version:   CurrentVersion,
+			GoPrivate: "example.com",
+			Env:       map[string]string{},
+		},
+		"v2.yaml": {
+			Version:     CurrentVersion,
+			Go111Module: "auto",
+			GoPrivate:   "example.com",
+			GoProxy:     "https://proxy.example.com,direct",
+			GoNoSumDB:   "example.com/*",
+			GoNoProxy:   "example.com",
+			GoFlags:     "-mod=mod -trimpath",
+			GoOS:        "linux",
+			GoArch:      "arm64",
+			CgoEnabled:  "0",
+			GoWork:      "off",
+			GoToolchain: "go1.21.0+auto",
+			Env:         map[string]string{"PROMPT": "$$ ", "HOME_BIN": "~/bin"},
+		},
+	}
+	for name, c := range want {
+		got, err := ReadConfig(filepath.Join("testdata", name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, c, got, name)
+	}
+}
+
+func TestReadConfigInvalid(t *testing.T) {
+	_, err := ReadConfig(filepath.Join("testdata", "unknown.yaml"))
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	assert.Contains(t, err.Error(), `unknown.yaml:3: unknown key "goprivat"`)
+
+	_, err = ReadConfig(filepath.Join("testdata", "unknown-path.yaml"))
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	assert.Contains(t, err.Error(), `unknown-path.yaml:3: unknown key "path.prepnd"`)
+
+	_, err = ReadConfig(filepath.Join("testdata", "unknown-pathlist.yaml"))
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	assert.Contains(t, err.Error(), `unknown-pathlist.yaml:5: unknown key "pathlists.LD_LIBRARY_PATH.apend"`)
+
+	_, err = ReadConfig(filepath.Join("testdata", "newer.yaml"))
+	assert.True(t, errors.Is(err, ErrNewerVersion))
+
+	_, err = ReadConfig(filepath.Join("testdata", "badtype.yaml"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+
+	_, err = ReadConfig(filepath.Join("testdata", "badvalue.yaml"))
+	assert.True(t, errors.Is(err, ErrInvalidValue))
+	assert.Contains(t, err.Error(), "goflags")
+
+	_, err = ReadConfig(filepath.Join("testdata", "missing.yaml"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpgrade(t *testing.T) {
+	filename, cleanup := copyFixture(t, "v0.yaml")
+	defer cleanup()
+	original, _ := ioutil.ReadFile(filename)
+
+	from, err := Upgrade(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, from)
+
+	backup, err := ioutil.ReadFile(filename + ".bak")
+	assert.NoError(t, err)
+	assert.Equal(t, original, backup)
+	upgraded, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(upgraded), "# written by gopr before versioning\nversion: 3\ngo111module: \"on\"\n"), string(upgraded))
+
+	from, err = Upgrade(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentVersion, from)
+}
+
+func TestUpgradeTilde(t *testing.T) {
+	filename, cleanup := copyFixture(t, "v2-tilde.yaml")
+	defer cleanup()
+
+	from, err := Upgrade(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, from)
+	c, err := ReadConfig(filename)
+	assert.NoError(t, err)
+	// only $ is escaped, a leading ~ is left to mean the home directory
+	assert.Equal(t, map[string]string{
+		"HOME_DIR":   "~",
+		"HOME_BIN":   "~/bin",
+		"OTHER_USER": "~user/bin",
+		"INSIDE":     "/opt/~/bin",
+		"PRICE":      "$$5 ~ $$10",
+	}, c.Env)
+
+	env, err := (&Expander{Home: "/home/me"}).Expand(c.Env)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"HOME_DIR":   "/home/me",
+		"HOME_BIN":   "/home/me/bin",
+		"OTHER_USER": "~user/bin",
+		"INSIDE":     "/opt/~/bin",
+		"PRICE":      "$5 ~ $10",
+	}, env)
+}
+
+func TestUpgradeFalse(t *testing.T) {
+	filename, cleanup := copyFixture(t, "v1-off.yaml")
+	defer cleanup()
+
+	from, err := Upgrade(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, from)
+	upgraded, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "version: 3\ngoprivate: example.com\nenv: {}\n", string(upgraded))
+}
+
+func TestWriteConfig(t *testing.T) {
+	filename, cleanup := copyFixture(t, "v1.yaml")
+	defer cleanup()
+
+	c, err := ReadConfig(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteConfig(c, filename))
+	again, err := ReadConfig(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, c, again)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kmpm/gopr/lib/pathlist"
+	"github.com/kmpm/gopr/lib/shell"
+)
+
+var (
+	// pathDirectives are the keys of a PathList
+	pathDirectives = []string{"prepend", "append", "remove"}
+)
+
+//PathList changes a list of directories like PATH. The directories can
+//refer to variables like env values do.
+type PathList struct {
+	Prepend []string `yaml:"prepend,omitempty"`
+	Append  []string `yaml:"append,omitempty"`
+	Remove  []string `yaml:"remove,omitempty"`
+}
+
+//Edit returns l as a pathlist.Edit with the references in the directories
+//resolved by resolve
+func (l PathList) Edit(resolve func(string) (string, error)) (pathlist.Edit, error) {
+	var e pathlist.Edit
+	lists := []*[]string{&e.Prepend, &e.Append, &e.Remove}
+	for i, dirs := range [][]string{l.Prepend, l.Append, l.Remove} {
+		for _, d := range dirs {
+			r, err := resolve(d)
+			if err != nil {
+				return e, err
+			}
+			*lists[i] = append(*lists[i], r)
+		}
+	}
+	return e, nil
+}
+
+//Empty reports whether l changes nothing
+func (l PathList) Empty() bool {
+	return len(l.Prepend) == 0 && len(l.Append) == 0 && len(l.Remove) == 0
+}
+
+//overlay changes l to have the effect of applying l and then o. The
+//directories o prepends come before the ones of l and the ones it appends
+//after them. A directory o adds is no longer removed and one it removes is
+//no longer added.
+func (l *PathList) overlay(o PathList) {
+	changed := append(append(append([]string{}, o.Prepend...), o.Append...), o.Remove...)
+	added := append(append([]string{}, o.Prepend...), o.Append...)
+	l.Prepend = pathlist.Unique(append(append([]string{}, o.Prepend...), pathlist.Remove(l.Prepend, changed...)...))
+	l.Append = pathlist.Unique(append(pathlist.Remove(l.Append, changed...), o.Append...))
+	l.Remove = pathlist.Unique(append(pathlist.Remove(l.Remove, added...), o.Remove...))
+	for _, dirs := range []*[]string{&l.Prepend, &l.Append, &l.Remove} {
+		if len(*dirs) == 0 {
+			*dirs = nil
+		}
+	}
+}
+
+//check returns an error for directories that can not be part of a list
+func (l PathList) check(key string) error {
+	for i, dirs := range [][]string{l.Prepend, l.Append, l.Remove} {
+		for _, d := range dirs {
+			if err := checkPathDir(d); err != nil {
+				return fmt.Errorf("%w for %s.%s: %v", ErrInvalidValue, key, pathDirectives[i], err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkPathDir(d string) error {
+	if strings.TrimSpace(d) == "" {
+		return fmt.Errorf("empty directory")
+	}
+	if strings.ContainsRune(d, os.PathListSeparator) {
+		return fmt.Errorf("%q contains the list separator %q", d, os.PathListSeparator)
+	}
+	_, err := (&Expander{}).ExpandValue(d, nil)
+	return err
+}
+
+//checkPaths validates path and pathlists
+func (c *Config) checkPaths() error {
+	if err := c.Path.check("path"); err != nil {
+		return err
+	}
+	for name, l := range c.PathLists {
+		if err := checkPathListName(name); err != nil {
+			return err
+		}
+		if err := l.check("pathlists." + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPathListName(name string) error {
+	if !shell.ValidName(name) {
+		return fmt.Errorf("%w: %q is not a variable name", ErrInvalidValue, name)
+	}
+	if strings.EqualFold(name, "PATH") {
+		return fmt.Errorf("%w: use path for PATH", ErrInvalidValue)
+	}
+	return nil
+}
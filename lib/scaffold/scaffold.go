@@ -0,0 +1,136 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	// TemplateSuffix is removed from file names when they are rendered. It
+	// lets templates contain files like go.mod that can not be embedded as is
+	TemplateSuffix = ".tmpl"
+)
+
+var (
+	// ErrUnknownTemplate - There is no user defined or built-in template with the name
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrInvalidTemplateName - The name can not be used for a template
+	ErrInvalidTemplateName = errors.New("invalid template name")
+
+	//go:embed all:templates
+	builtin embed.FS
+)
+
+//Data is what templates can refer to
+type Data struct {
+	// Name of the project
+	Name string
+	// ProjectPath is the directory of the project
+	ProjectPath string
+	// GoPath is the GOPATH of the project
+	GoPath string
+	// Module is the module path used in go.mod
+	Module string
+}
+
+//Builtin returns the names of the templates that are part of gopr
+func Builtin() []string {
+	entries, _ := builtin.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Find returns the template called name. A directory with the name in
+//userDir takes precedence over the built-in templates.
+func Find(userDir, name string) (fs.FS, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, ErrInvalidTemplateName
+	}
+	if userDir != "" {
+		dir := filepath.Join(userDir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return os.DirFS(dir), nil
+		}
+	}
+	if info, err := fs.Stat(builtin, path.Join("templates", name)); err == nil && info.IsDir() {
+		return fs.Sub(builtin, path.Join("templates", name))
+	}
+	return nil, ErrUnknownTemplate
+}
+
+//Render writes every file in tmpl to dest. Both the file names and the
+//contents are executed as text/template with data.
+func Render(tmpl fs.FS, dest string, data Data) error {
+	return fs.WalkDir(tmpl, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == "." {
+			return err
+		}
+		name, err := execute(p, p, data)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, filepath.FromSlash(strings.TrimSuffix(name, TemplateSuffix)))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		body, err := fs.ReadFile(tmpl, p)
+		if err != nil {
+			return err
+		}
+		out, err := execute(p, string(body), data)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if info, err := d.Info(); err == nil && info.Mode().Perm()&0111 != 0 {
+			mode = 0755
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, []byte(out), mode)
+	})
+}
+
+func execute(name, text string, data Data) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
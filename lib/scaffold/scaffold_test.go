@@ -0,0 +1,65 @@
+package scaffold
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltin(t *testing.T) {
+	assert.Equal(t, []string{"module", "tools"}, Builtin())
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scaffold")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "module"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "module", "user"), nil, 0644))
+
+	tmpl, err := Find(dir, "module")
+	assert.NoError(t, err)
+	_, err = tmpl.Open("user")
+	assert.NoError(t, err, "user templates take precedence")
+
+	_, err = Find(dir, "tools")
+	assert.NoError(t, err)
+	_, err = Find(dir, "missing")
+	assert.Equal(t, ErrUnknownTemplate, err)
+	_, err = Find(dir, "../module")
+	assert.Equal(t, ErrInvalidTemplateName, err)
+}
+
+func TestRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scaffold")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tmpl, err := Find("", "module")
+	assert.NoError(t, err)
+	data := Data{Name: "demo", ProjectPath: dir, GoPath: filepath.Join(dir, "go"), Module: "example.com/demo"}
+	assert.NoError(t, Render(tmpl, dir, data))
+
+	gomod, err := ioutil.ReadFile(filepath.Join(dir, "src", "demo", "go.mod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "module example.com/demo\n", string(gomod))
+	main, err := ioutil.ReadFile(filepath.Join(dir, "src", "demo", "main.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(main), `"Hello from demo"`)
+	_, err = os.Stat(filepath.Join(dir, "project.yaml"))
+	assert.NoError(t, err)
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scaffold")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "tmpl")
+	assert.NoError(t, os.MkdirAll(src, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "README"), []byte("{{ .Nope }}"), 0644))
+
+	assert.Error(t, Render(os.DirFS(src), filepath.Join(dir, "out"), Data{}))
+}
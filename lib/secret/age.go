@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/kmpm/gopr/lib/envfile"
+)
+
+var (
+	// ErrNoRecipients - The identities can not be used to encrypt
+	ErrNoRecipients = errors.New("no identity to encrypt to")
+)
+
+//ReadIdentities reads age identities from a file like the ones written by
+//age-keygen or GenerateIdentity
+func ReadIdentities(filename string) ([]age.Identity, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return ids, nil
+}
+
+//GenerateIdentity writes a new identity to filename, which must not
+//exist, readable only by the user
+func GenerateIdentity(filename string) (*age.X25519Identity, error) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(f, "# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), id.Recipient(), id)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+//Recipients returns the recipients of the X25519 identities in ids, the
+//ones an encrypted file has to be made for so ids can read it
+func Recipients(ids []age.Identity) []age.Recipient {
+	var rs []age.Recipient
+	for _, id := range ids {
+		if x, ok := id.(*age.X25519Identity); ok {
+			rs = append(rs, x.Recipient())
+		}
+	}
+	return rs
+}
+
+//ReadEnvFile decrypts filename with ids and parses it as a dotenv file.
+//Both binary and armored files can be read.
+func ReadEnvFile(filename string, ids []age.Identity) ([]envfile.Var, error) {
+	f, src, err := openEncrypted(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := age.Decrypt(src, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	vars, err := envfile.ReadDotenv(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return vars, nil
+}
+
+//CountRecipients returns how many recipients filename is encrypted to,
+//the number of stanzas in its header
+func CountRecipients(filename string) (int, error) {
+	f, src, err := openEncrypted(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(src)
+	n := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("%s: invalid header: %w", filename, err)
+		}
+		switch {
+		case strings.HasPrefix(line, "-> "):
+			n++
+		case strings.HasPrefix(line, "--- "):
+			return n, nil
+		}
+	}
+}
+
+//openEncrypted opens filename and returns the file, to be closed, and the
+//age encrypted content, unarmored if needed
+func openEncrypted(filename string) (*os.File, io.Reader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	br := bufio.NewReader(f)
+	if start, _ := br.Peek(len(armor.Header)); string(start) == armor.Header {
+		return f, armor.NewReader(br), nil
+	}
+	return f, br, nil
+}
+
+//WriteEnvFile encrypts vars as a dotenv file to recipients and saves it
+//armored, so it can be kept in version control, to filename
+func WriteEnvFile(filename string, vars []envfile.Var, recipients []age.Recipient) error {
+	if len(recipients) == 0 {
+		return ErrNoRecipients
+	}
+	var plain bytes.Buffer
+	if err := envfile.Write(&plain, "dotenv", vars); err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	a := armor.NewWriter(&out)
+	w, err := age.Encrypt(a, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(plain.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := a.Close(); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(out.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
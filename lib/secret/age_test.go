@@ -0,0 +1,74 @@
+package secret
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/kmpm/gopr/lib/envfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	idFile := filepath.Join(dir, "identity.txt")
+	id, err := GenerateIdentity(idFile)
+	assert.NoError(t, err)
+	_, err = GenerateIdentity(idFile)
+	assert.True(t, os.IsExist(err), "%v", err)
+	ids, err := ReadIdentities(idFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []age.Identity{id}, ids)
+
+	filename := filepath.Join(dir, "project.env.age")
+	vars := []envfile.Var{{Key: "TOKEN", Value: "s3cr$t"}, {Key: "MULTI", Value: "a\nb"}}
+	assert.NoError(t, WriteEnvFile(filename, vars, Recipients(ids)))
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "-----BEGIN AGE ENCRYPTED FILE-----\n"))
+	assert.NotContains(t, string(data), "s3cr")
+
+	got, err := ReadEnvFile(filename, ids)
+	assert.NoError(t, err)
+	assert.Equal(t, vars, got)
+
+	other, _ := age.GenerateX25519Identity()
+	_, err = ReadEnvFile(filename, []age.Identity{other})
+	assert.Error(t, err)
+
+	n, err := CountRecipients(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, WriteEnvFile(filename, vars, append(Recipients(ids), other.Recipient())))
+	n, err = CountRecipients(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, ErrNoRecipients, WriteEnvFile(filename, vars, nil))
+}
+
+func TestReadBinaryEnvFile(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, id.Recipient())
+	assert.NoError(t, err)
+	w.Write([]byte("A=b\n"))
+	assert.NoError(t, w.Close())
+
+	f, err := ioutil.TempFile("", "binary-*.age")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Write(buf.Bytes())
+	f.Close()
+
+	got, err := ReadEnvFile(f.Name(), []age.Identity{id})
+	assert.NoError(t, err)
+	assert.Equal(t, []envfile.Var{{Key: "A", Value: "b"}}, got)
+}
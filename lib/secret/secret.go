@@ -0,0 +1,196 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// Scheme starts values that refer to a secret, like secret://file/<path>
+	Scheme = "secret://"
+	// Mask is shown instead of the value of a secret
+	Mask = "********"
+)
+
+var (
+	// ErrUnknownProvider - The reference names a provider that is not registered
+	ErrUnknownProvider = errors.New("unknown secret provider")
+	// ErrInvalidReference - The reference is not provider/name
+	ErrInvalidReference = errors.New("invalid secret reference")
+	// ErrUnsupported - The provider can not be used on this system
+	ErrUnsupported = errors.New("secret provider not supported on " + runtime.GOOS)
+
+	providers = map[string]Provider{
+		"file":    ProviderFunc(fromFile),
+		"cmd":     ProviderFunc(fromCommand),
+		"pass":    ProviderFunc(fromPass),
+		"keyring": ProviderFunc(fromKeyring),
+	}
+)
+
+//Provider looks up secrets by name
+type Provider interface {
+	Lookup(name string) (string, error)
+}
+
+//ProviderFunc lets a plain function be used as a Provider
+type ProviderFunc func(name string) (string, error)
+
+//Lookup calls f(name)
+func (f ProviderFunc) Lookup(name string) (string, error) {
+	return f(name)
+}
+
+//Register makes p available as provider name, replacing any earlier one
+func Register(name string, p Provider) {
+	providers[name] = p
+}
+
+//Providers returns the names of the registered providers
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//IsRef reports whether value refers to a secret
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+//Parse splits the reference value into the provider and the name the
+//provider looks up
+func Parse(value string) (string, string, error) {
+	ref := strings.TrimPrefix(value, Scheme)
+	i := strings.IndexByte(ref, '/')
+	if !IsRef(value) || i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("%w: %q, use %sprovider/name", ErrInvalidReference, value, Scheme)
+	}
+	provider, name := ref[:i], ref[i+1:]
+	if _, ok := providers[provider]; !ok {
+		return "", "", fmt.Errorf("%w %q, use one of %s", ErrUnknownProvider, provider, strings.Join(Providers(), ", "))
+	}
+	return provider, name, nil
+}
+
+//Resolve returns the secret value refers to. Values that are not
+//references are returned as they are.
+func Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	provider, name, err := Parse(value)
+	if err != nil {
+		return "", err
+	}
+	s, err := providers[provider].Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("%s%s/%s: %w", Scheme, provider, name, err)
+	}
+	return s, nil
+}
+
+//Redact returns Mask for references and value for anything else. The
+//reference is checked but the secret is not looked up.
+func Redact(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	if _, _, err := Parse(value); err != nil {
+		return "", err
+	}
+	return Mask, nil
+}
+
+//fromFile reads the secret from the file at path, which must be absolute
+//or start with ~/. A trailing newline is dropped.
+func fromFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: %q is not an absolute path", ErrInvalidReference, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(string(data)), nil
+}
+
+//fromCommand runs command with the shell of the system and uses what it
+//writes to stdout. stderr goes to the terminal so the command can prompt.
+func fromCommand(command string) (string, error) {
+	if runtime.GOOS == "windows" {
+		return output(exec.Command("cmd", "/C", command))
+	}
+	return output(exec.Command("sh", "-c", command))
+}
+
+//fromPass reads the first line of an entry in the pass password store
+func fromPass(entry string) (string, error) {
+	s, err := output(exec.Command("pass", "show", entry))
+	if err != nil {
+		return "", err
+	}
+	return strings.SplitN(s, "\n", 2)[0], nil
+}
+
+//fromKeyring reads the password of account name for the service gopr
+//from the keyring of the desktop, with secret-tool on linux and security
+//on macOS
+func fromKeyring(name string) (string, error) {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return output(exec.Command("secret-tool", "lookup", "service", "gopr", "account", name))
+	case "darwin":
+		return output(exec.Command("security", "find-generic-password", "-s", "gopr", "-a", name, "-w"))
+	}
+	return "", ErrUnsupported
+}
+
+//output runs cmd and returns stdout without the trailing newline
+func output(cmd *exec.Cmd) (string, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", filepath.Base(cmd.Path), err)
+	}
+	return trimNewline(out.String()), nil
+}
+
+func trimNewline(s string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+}
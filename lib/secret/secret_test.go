@@ -0,0 +1,75 @@
+package secret
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	provider, name, err := Parse("secret://file//run/secrets/token")
+	assert.NoError(t, err)
+	assert.Equal(t, "file", provider)
+	assert.Equal(t, "/run/secrets/token", name)
+
+	for _, v := range []string{"secret://", "secret://file", "secret://file/", "secret:///x", "file/x"} {
+		_, _, err := Parse(v)
+		assert.True(t, errors.Is(err, ErrInvalidReference), v)
+	}
+	_, _, err = Parse("secret://vault/x")
+	assert.True(t, errors.Is(err, ErrUnknownProvider))
+}
+
+func TestResolveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(f, []byte("s3cr$t\n"), 0600))
+
+	s, err := Resolve("secret://file/" + f)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr$t", s)
+
+	_, err = Resolve("secret://file/" + filepath.Join(dir, "missing"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+	_, err = Resolve("secret://file/token")
+	assert.True(t, errors.Is(err, ErrInvalidReference))
+}
+
+func TestResolveCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	s, err := Resolve("secret://cmd/printf 'a b\\n'")
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", s)
+
+	_, err = Resolve("secret://cmd/exit 3")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3")
+}
+
+func TestResolvePlain(t *testing.T) {
+	s, err := Resolve("plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", s)
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", ProviderFunc(func(name string) (string, error) { return "<" + name + ">", nil }))
+	defer delete(providers, "test")
+
+	s, err := Resolve("secret://test/a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "<a/b>", s)
+
+	s, err = Redact("secret://test/a")
+	assert.NoError(t, err)
+	assert.Equal(t, Mask, s)
+}
@@ -0,0 +1,133 @@
+package shell
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// evaluator runs a script generated for a shell and prints a variable
+type evaluator struct {
+	shell string
+	// command returns the arguments that evaluate file the way the usage
+	// hint tells users to and then print GOPR_FUZZ without a newline
+	command func(file string) []string
+	// utf8 is set for shells that only handle valid UTF-8
+	utf8 bool
+}
+
+var evaluators = []evaluator{
+	{"sh", func(f string) []string {
+		return []string{"sh", "-c", `eval "$(cat '` + f + `')"; printf '%s' "$GOPR_FUZZ"`}
+	}, false},
+	{"bash", func(f string) []string {
+		return []string{"bash", "--norc", "-c", `eval "$(cat '` + f + `')"; printf '%s' "$GOPR_FUZZ"`}
+	}, false},
+	{"zsh", func(f string) []string {
+		return []string{"zsh", "-f", "-c", `eval "$(cat '` + f + `')"; printf '%s' "$GOPR_FUZZ"`}
+	}, false},
+	{"fish", func(f string) []string {
+		return []string{"fish", "--no-config", "-c", `cat '` + f + `' | source; printf '%s' "$GOPR_FUZZ"`}
+	}, true},
+	{"tcsh", func(f string) []string {
+		return []string{"tcsh", "-f", "-c", "eval \"`cat '" + f + "'`\"; printenv GOPR_FUZZ"}
+	}, true},
+	{"pwsh", func(f string) []string {
+		return []string{"pwsh", "-NoProfile", "-Command", `& cat '` + f + `' | Out-String | Invoke-Expression; [Console]::Out.Write($Env:GOPR_FUZZ)`}
+	}, true},
+	{"elvish", func(f string) []string {
+		return []string{"elvish", "-norc", "-c", `eval (cat '` + f + `' | slurp); print (get-env GOPR_FUZZ)`}
+	}, true},
+	{"nu", func(f string) []string {
+		return []string{"nu", "--no-config-file", "-c", `source '` + f + `'; print -n $env.GOPR_FUZZ`}
+	}, true},
+	{"emacs", func(f string) []string {
+		return []string{"emacs", "--batch", "--eval", `(progn (load "` + f + `" nil t t) (princ (getenv "GOPR_FUZZ")))`}
+	}, true},
+}
+
+var quoteSeeds = []string{
+	"",
+	"plain",
+	"two  spaces\tand a tab",
+	`double "quotes"`,
+	"single 'quotes'",
+	"$HOME ${HOME} $(id) `id`",
+	`back\slash \\ \n \' \"`,
+	"%PATH% %% ^& | < > !",
+	"line\nbreak\n",
+	"trailing newline\n\n",
+	"#not a comment; echo injected",
+	"*?[glob]~",
+	"unicode åäö ‘’ 日本",
+	"'\\''",
+	"\x01\x1f\x7f",
+}
+
+func FuzzQuote(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+
+	dir, err := ioutil.TempDir("", "gopr-quote")
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	available := []evaluator{}
+	for _, e := range evaluators {
+		if _, err := exec.LookPath(e.command("")[0]); err == nil {
+			available = append(available, e)
+		}
+	}
+	if len(available) == 0 {
+		f.Skip("no shells available")
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, e := range available {
+			s, err := Get(e.shell)
+			assert.NoError(t, err)
+			if Validate(s, "GOPR_FUZZ", value) != nil || (e.utf8 && !utf8.ValidString(value)) {
+				continue
+			}
+			file := filepath.Join(dir, e.shell)
+			assert.NoError(t, ioutil.WriteFile(file, []byte(s.Set("GOPR_FUZZ", value)+"\n"), 0644))
+
+			args := e.command(file)
+			out, err := exec.Command(args[0], args[1:]...).Output()
+			if !assert.NoError(t, err, "%s: %q", e.shell, value) {
+				continue
+			}
+			actual := string(out)
+			if e.shell == "tcsh" {
+				actual = strings.TrimSuffix(actual, "\n")
+			}
+			assert.Equal(t, value, actual, e.shell)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	sh, _ := Get("sh")
+	cmd, _ := Get("cmd")
+
+	for _, name := range []string{"GOPATH", "_x", "a1", "GO111MODULE"} {
+		assert.NoError(t, Validate(sh, name, "v"), name)
+	}
+	for _, name := range []string{"", "1A", "A-B", "A B", "A=B", "$(id)", "Å"} {
+		assert.True(t, errors.Is(Validate(sh, name, "v"), ErrInvalidName), name)
+	}
+
+	assert.NoError(t, Validate(sh, "A", "multi\nline"))
+	assert.True(t, errors.Is(Validate(sh, "A", "nul\x00"), ErrInvalidValue))
+	assert.True(t, errors.Is(Validate(cmd, "A", "multi\nline"), ErrInvalidValue))
+}
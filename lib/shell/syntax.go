@@ -0,0 +1,362 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Shell generates statements in the language of a specific shell
+type Shell interface {
+	// Name returns the name the shell is registered under
+	Name() string
+	// Set returns a statement exporting key with value
+	Set(key, value string) string
+	// Unset returns a statement removing key from the environment
+	Unset(key string) string
+	// Comment returns text as a comment
+	Comment(text string) string
+	// Quote returns value as a string literal that evaluates to exactly value
+	Quote(value string) string
+	// Eval returns how to evaluate the output of command in the shell
+	Eval(command string) string
+}
+
+var (
+	// ErrUnknownShell - There is no Shell registered with the name
+	ErrUnknownShell = errors.New("unknown shell")
+	// ErrInvalidName - The name can not be used for an environment variable
+	ErrInvalidName = errors.New("invalid variable name")
+	// ErrInvalidValue - The value can not be represented in the shell
+	ErrInvalidValue = errors.New("invalid variable value")
+
+	registry = make(map[string]Shell)
+)
+
+func init() {
+	Register(posix{}, "sh", "bash", "zsh", "dash", "ksh", "mksh", "ash", "busybox")
+	Register(fish{}, "fish")
+	Register(tcsh{}, "tcsh", "csh")
+	Register(powershell{}, "powershell", "pwsh")
+	Register(cmd{}, "cmd")
+	Register(emacs{}, "emacs")
+	Register(nushell{}, "nu", "nushell")
+	Register(elvish{}, "elvish")
+}
+
+// Register makes s available under its own name and any aliases
+func Register(s Shell, aliases ...string) {
+	registry[s.Name()] = s
+	for _, a := range aliases {
+		registry[a] = s
+	}
+}
+
+// Get returns the Shell registered as name
+func Get(name string) (Shell, error) {
+	if s, ok := registry[name]; ok {
+		return s, nil
+	}
+	return nil, ErrUnknownShell
+}
+
+// Names returns all registered names and aliases
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// valueChecker is implemented by shells that can not represent every value
+type valueChecker interface {
+	checkValue(value string) error
+}
+
+// Validate checks that key is a portable variable name and that s can
+// represent value exactly
+func Validate(s Shell, key, value string) error {
+	if !ValidName(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, key)
+	}
+	if strings.ContainsRune(value, 0) {
+		return fmt.Errorf("%w: %s contains a NUL character", ErrInvalidValue, key)
+	}
+	if c, ok := s.(valueChecker); ok {
+		if err := c.checkValue(value); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrInvalidValue, key, err)
+		}
+	}
+	return nil
+}
+
+// ValidName reports whether key is a letter or underscore followed by
+// letters, digits and underscores
+func ValidName(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// posix is sh and the shells compatible with it
+type posix struct{}
+
+func (posix) Name() string { return "sh" }
+
+func (s posix) Set(key, value string) string {
+	return fmt.Sprintf("export %s=%s", key, s.Quote(value))
+}
+
+func (posix) Unset(key string) string {
+	return "unset " + key
+}
+
+func (posix) Comment(text string) string {
+	return "# " + text
+}
+
+func (posix) Quote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+func (posix) Eval(command string) string {
+	return fmt.Sprintf("eval \"$(%s)\"", command)
+}
+
+// fish is the friendly interactive shell
+type fish struct{}
+
+func (fish) Name() string { return "fish" }
+
+func (s fish) Set(key, value string) string {
+	return fmt.Sprintf("set -gx %s %s;", key, s.Quote(value))
+}
+
+func (fish) Unset(key string) string {
+	return fmt.Sprintf("set -e %s;", key)
+}
+
+func (fish) Comment(text string) string {
+	return "# " + text
+}
+
+func (fish) Quote(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return "'" + strings.Replace(value, "'", `\'`, -1) + "'"
+}
+
+func (fish) Eval(command string) string {
+	return command + " | source"
+}
+
+// tcsh is tcsh and csh. The output is evaluated with backticks which joins
+// all lines, so every statement ends with a semicolon, comments are made
+// with the null command and values can not contain newlines
+type tcsh struct{}
+
+func (tcsh) Name() string { return "tcsh" }
+
+func (s tcsh) Set(key, value string) string {
+	return fmt.Sprintf("setenv %s %s;", key, s.Quote(value))
+}
+
+func (tcsh) Unset(key string) string {
+	return fmt.Sprintf("unsetenv %s;", key)
+}
+
+func (s tcsh) Comment(text string) string {
+	return ": " + s.Quote(strings.Replace(text, "\n", " ", -1)) + ";"
+}
+
+func (tcsh) Quote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+func (tcsh) Eval(command string) string {
+	return fmt.Sprintf("eval \"`%s`\"", command)
+}
+
+func (tcsh) checkValue(value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.New("contains a line break")
+	}
+	return nil
+}
+
+// powershell is Windows PowerShell and PowerShell Core
+type powershell struct{}
+
+func (powershell) Name() string { return "powershell" }
+
+func (s powershell) Set(key, value string) string {
+	return fmt.Sprintf("$Env:%s = %s", key, s.Quote(value))
+}
+
+func (powershell) Unset(key string) string {
+	return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", key)
+}
+
+func (powershell) Comment(text string) string {
+	return "# " + text
+}
+
+func (powershell) Quote(value string) string {
+	// powershell treats the typographic single quotes like ' as well
+	for _, q := range []string{"'", "\u2018", "\u2019", "\u201a", "\u201b"} {
+		value = strings.Replace(value, q, q+q, -1)
+	}
+	return "'" + value + "'"
+}
+
+func (powershell) Eval(command string) string {
+	return fmt.Sprintf("& %s | Out-String | Invoke-Expression", command)
+}
+
+// cmd is the Windows command prompt. The output is evaluated line by line
+// with FOR /f which substitutes the line after special characters have been
+// parsed, so values are used as is but can not contain newlines.
+// CALL gives a second round of variable expansion
+type cmd struct{}
+
+func (cmd) Name() string { return "cmd" }
+
+func (s cmd) Set(key, value string) string {
+	return fmt.Sprintf("SET %s=%s", key, s.Quote(value))
+}
+
+func (cmd) Unset(key string) string {
+	return fmt.Sprintf("SET %s=", key)
+}
+
+func (cmd) Comment(text string) string {
+	return "REM " + text
+}
+
+func (cmd) Quote(value string) string {
+	return value
+}
+
+func (cmd) checkValue(value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.New("contains a line break")
+	}
+	return nil
+}
+
+func (cmd) Eval(command string) string {
+	return fmt.Sprintf("@FOR /f \"tokens=*\" %%i IN ('%s') DO @%%i", command)
+}
+
+// emacs is emacs lisp, for use with eval-buffer
+type emacs struct{}
+
+func (emacs) Name() string { return "emacs" }
+
+func (s emacs) Set(key, value string) string {
+	return fmt.Sprintf("(setenv %s %s)", s.Quote(key), s.Quote(value))
+}
+
+func (s emacs) Unset(key string) string {
+	return fmt.Sprintf("(setenv %s)", s.Quote(key))
+}
+
+func (emacs) Comment(text string) string {
+	return ";; " + text
+}
+
+func (emacs) Quote(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
+
+func (emacs) Eval(command string) string {
+	return fmt.Sprintf("(with-temp-buffer (shell-command \"%s\" (current-buffer)) (eval-buffer))", command)
+}
+
+// nushell can not evaluate strings, the output has to be saved and sourced
+type nushell struct{}
+
+func (nushell) Name() string { return "nu" }
+
+func (s nushell) Set(key, value string) string {
+	if key == "PATH" || key == "Path" {
+		// nushell keeps PATH as a list
+		return fmt.Sprintf("$env.%s = (%s | split row (char esep))", key, s.Quote(value))
+	}
+	return fmt.Sprintf("$env.%s = %s", key, s.Quote(value))
+}
+
+func (nushell) Unset(key string) string {
+	return fmt.Sprintf("hide-env -i %s", key)
+}
+
+func (nushell) Comment(text string) string {
+	return "# " + text
+}
+
+func (nushell) Quote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&b, `\u{%x}`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func (nushell) Eval(command string) string {
+	return fmt.Sprintf("%s | save --force gopr-env.nu; source gopr-env.nu", command)
+}
+
+// elvish is the elvish shell
+type elvish struct{}
+
+func (elvish) Name() string { return "elvish" }
+
+func (s elvish) Set(key, value string) string {
+	return fmt.Sprintf("set-env %s %s", key, s.Quote(value))
+}
+
+func (elvish) Unset(key string) string {
+	return "unset-env " + key
+}
+
+func (elvish) Comment(text string) string {
+	return "# " + text
+}
+
+func (elvish) Quote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+func (elvish) Eval(command string) string {
+	return fmt.Sprintf("eval (%s | slurp)", command)
+}
@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// script exercises every method of s the way gopr env would
+func script(s Shell) string {
+	lines := []string{
+		s.Comment("gopr test script"),
+		s.Set("GOPATH", "/home/user/.gopr/foo/go"),
+		s.Set("GOPRIVATE", ""),
+		s.Set("PATH", "/home/user/.gopr/foo/go/bin:/usr/bin"),
+		s.Unset("GOPR_OLD_GOPATH"),
+		s.Comment(s.Eval("gopr env foo")),
+		s.Quote("value"),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestGolden(t *testing.T) {
+	for _, name := range []string{"sh", "fish", "tcsh", "powershell", "cmd", "emacs", "nu", "elvish"} {
+		t.Run(name, func(t *testing.T) {
+			s, err := Get(name)
+			assert.NoError(t, err)
+			assert.Equal(t, name, s.Name())
+
+			golden := filepath.Join("testdata", name+".golden")
+			actual := script(s)
+			if *update {
+				assert.NoError(t, ioutil.WriteFile(golden, []byte(actual), 0644))
+			}
+			expected, err := ioutil.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), actual)
+		})
+	}
+}
+
+func TestGetAliases(t *testing.T) {
+	for alias, name := range map[string]string{"bash": "sh", "zsh": "sh", "pwsh": "powershell", "csh": "tcsh", "nushell": "nu"} {
+		s, err := Get(alias)
+		assert.NoError(t, err)
+		assert.Equal(t, name, s.Name())
+	}
+
+	_, err := Get("command.com")
+	assert.Equal(t, ErrUnknownShell, err)
+	assert.Contains(t, Names(), "bash")
+}
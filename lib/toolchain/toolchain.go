@@ -0,0 +1,416 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package toolchain
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kmpm/gopr/lib/fsutil"
+)
+
+const (
+	// DefaultMirror is the base url go distributions are downloaded from
+	DefaultMirror = "https://dl.google.com/go"
+)
+
+var (
+	// ErrInvalidVersion - The given string is not a go version
+	ErrInvalidVersion = errors.New("invalid go version")
+	// ErrNotInstalled - The requested version is not in the cache
+	ErrNotInstalled = errors.New("toolchain not installed")
+	// ErrNoDistribution - The archive did not contain a go distribution
+	ErrNoDistribution = errors.New("archive does not contain a go distribution")
+	// ErrChecksum - The archive does not have the expected SHA256 checksum
+	ErrChecksum = errors.New("checksum mismatch")
+
+	versionRe = regexp.MustCompile(`^go1(\.[0-9]+){0,2}((beta|rc)[0-9]+)?$`)
+	sumRe     = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	// httpClient gives up on a mirror that stalls, the timeout is long
+	// enough to download an SDK over a slow connection
+	httpClient = &http.Client{Timeout: 30 * time.Minute}
+)
+
+//Normalize returns version with a "go" prefix, 1.14.1 becomes go1.14.1
+func Normalize(version string) string {
+	if version == "" || strings.HasPrefix(version, "go") {
+		return version
+	}
+	return "go" + version
+}
+
+//Valid reports whether version looks like a released go version
+func Valid(version string) bool {
+	return versionRe.MatchString(Normalize(version))
+}
+
+//Dir returns the GOROOT of version inside the cache at root
+func Dir(root, version string) string {
+	return filepath.Join(root, Normalize(version))
+}
+
+//Installed reports whether version has been installed in the cache at root
+func Installed(root, version string) bool {
+	_, err := os.Stat(goBinary(Dir(root, version)))
+	return err == nil
+}
+
+//Archive returns the file name of the official distribution for
+//version on the current platform
+func Archive(version string) string {
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("%s.%s-%s%s", Normalize(version), runtime.GOOS, runtime.GOARCH, ext)
+}
+
+//List returns the installed versions in the cache at root
+func List(root string) ([]string, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	versions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() && Installed(root, e.Name()) {
+			versions = append(versions, e.Name())
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+//Remove deletes version from the cache at root
+func Remove(root, version string) error {
+	if !Installed(root, version) {
+		return ErrNotInstalled
+	}
+	return fsutil.RemoveAll(Dir(root, version))
+}
+
+//Install puts version into the cache at root. The source can be the path
+//to a local archive or an http(s) url. If source is an url ending with a
+//slash, or a mirror without a file name, the platform archive name is
+//appended to it.
+//
+//The archive must have the SHA256 checksum sum, given as hex. Without sum
+//it is read from <archive>.sha256 next to the archive, which must exist
+//for a download and is optional for a local archive.
+func Install(root, version, source, sum string) error {
+	version = Normalize(version)
+	if !Valid(version) {
+		return ErrInvalidVersion
+	}
+	sum = strings.ToLower(sum)
+	if sum != "" && !sumRe.MatchString(sum) {
+		return fmt.Errorf("%w: %q is not a SHA256 checksum", ErrChecksum, sum)
+	}
+	if source == "" {
+		source = DefaultMirror
+	}
+	if err := os.MkdirAll(root, os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+
+	archive := source
+	if isURL(source) {
+		if !isArchive(source) {
+			source = strings.TrimSuffix(source, "/") + "/" + Archive(version)
+		}
+		if sum == "" {
+			var err error
+			if sum, err = downloadSum(source + ".sha256"); err != nil {
+				return err
+			}
+		}
+		tmp, err := download(root, source)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		archive = tmp
+	} else if sum == "" {
+		f, err := os.Open(source + ".sha256")
+		if err == nil {
+			sum, err = readSum(f)
+			f.Close()
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%s.sha256: %w", source, err)
+		}
+	}
+	if sum != "" {
+		if err := verify(archive, sum); err != nil {
+			return err
+		}
+	}
+
+	staging, err := ioutil.TempDir(root, ".install-")
+	if err != nil {
+		return err
+	}
+	defer fsutil.RemoveAll(staging)
+
+	if strings.HasSuffix(source, ".zip") {
+		err = extractZip(archive, staging)
+	} else {
+		err = extractTarGz(archive, staging)
+	}
+	if err != nil {
+		return err
+	}
+
+	goroot := filepath.Join(staging, "go")
+	if _, err := os.Stat(goBinary(goroot)); err != nil {
+		return ErrNoDistribution
+	}
+	if err := fsutil.RemoveAll(Dir(root, version)); err != nil {
+		return err
+	}
+	return os.Rename(goroot, Dir(root, version))
+}
+
+func goBinary(goroot string) string {
+	name := "go"
+	if runtime.GOOS == "windows" {
+		name = "go.exe"
+	}
+	return filepath.Join(goroot, "bin", name)
+}
+
+func isURL(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func isArchive(source string) bool {
+	return strings.HasSuffix(source, ".tar.gz") || strings.HasSuffix(source, ".zip")
+}
+
+func get(source string) (*http.Response, error) {
+	resp, err := httpClient.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading %s: %s", source, resp.Status)
+	}
+	return resp, nil
+}
+
+func download(dir, source string) (string, error) {
+	resp, err := get(source)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	f, err := ioutil.TempFile(dir, ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+//downloadSum fetches the checksum file of an archive from a mirror
+func downloadSum(source string) (string, error) {
+	resp, err := get(source)
+	if err != nil {
+		return "", fmt.Errorf("%v, give the checksum of the archive instead", err)
+	}
+	defer resp.Body.Close()
+	sum, err := readSum(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+	return sum, nil
+}
+
+//readSum reads the hex checksum at the start of a .sha256 file, which may
+//be followed by the file name like in the output of sha256sum
+func readSum(r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || !sumRe.MatchString(strings.ToLower(fields[0])) {
+		return "", fmt.Errorf("%w: no SHA256 checksum found", ErrChecksum)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+//verify checks that the SHA256 checksum of archive is sum
+func verify(archive, sum string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sum {
+		return fmt.Errorf("%w: archive has %s, expected %s", ErrChecksum, got, sum)
+	}
+	return nil
+}
+
+//escapes reports whether the archive name is absolute or goes up a
+//directory. Names and symlinks that never go up can not leave dest, not
+//even by following symlinks extracted before.
+func escapes(name string) bool {
+	name = filepath.ToSlash(name)
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return true
+	}
+	for _, e := range strings.Split(name, "/") {
+		if e == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+//target returns where name should be extracted in dest and makes sure
+//that it does not escape it
+func target(dest, name string) (string, error) {
+	p := filepath.Join(dest, filepath.FromSlash(name))
+	if escapes(name) || (p != dest && !strings.HasPrefix(p, dest+string(os.PathSeparator))) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return p, nil
+}
+
+func extractTarGz(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p, err := target(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, mode|0700)
+		case tar.TypeReg:
+			err = writeFile(p, tr, mode)
+		case tar.TypeSymlink:
+			if escapes(hdr.Linkname) {
+				return fmt.Errorf("illegal symlink in archive: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err = os.MkdirAll(filepath.Dir(p), 0755); err == nil {
+				err = os.Symlink(hdr.Linkname, p)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archive, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		p, err := target(dest, zf.Name)
+		if err != nil {
+			return err
+		}
+		if zf.FileInfo().IsDir() {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(p, r, zf.Mode().Perm())
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(p string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,192 @@
+package toolchain
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeDist creates a fake go distribution archive containing files, a
+// file given as "name -> target" is a symlink
+func writeDist(t *testing.T, filename string, files ...string) {
+	f, err := os.Create(filename)
+	assert.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		if i := strings.Index(name, " -> "); i > 0 {
+			assert.NoError(t, tw.WriteHeader(&tar.Header{
+				Name:     name[:i],
+				Linkname: name[i+4:],
+				Mode:     0777,
+				Typeflag: tar.TypeSymlink,
+			}))
+			continue
+		}
+		body := []byte("#!/bin/sh\n")
+		assert.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0755,
+			Size:     int64(len(body)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err = tw.Write(body)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+}
+
+// writeSum writes the .sha256 file of archive and returns the checksum
+func writeSum(t *testing.T, archive string) string {
+	data, err := ioutil.ReadFile(archive)
+	assert.NoError(t, err)
+	sum := fmt.Sprintf("%x", sha256.Sum256(data))
+	assert.NoError(t, ioutil.WriteFile(archive+".sha256", []byte(sum+"  "+filepath.Base(archive)+"\n"), 0644))
+	return sum
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "toolchain")
+	assert.NoError(t, err)
+	return dir
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "go1.14.1", Normalize("1.14.1"))
+	assert.Equal(t, "go1.14.1", Normalize("go1.14.1"))
+	assert.Equal(t, "", Normalize(""))
+}
+
+func TestValid(t *testing.T) {
+	for _, v := range []string{"1.14", "go1.14.1", "go1.15rc1", "1.16beta1"} {
+		assert.True(t, Valid(v), v)
+	}
+	for _, v := range []string{"", "latest", "go2", "1.14.1.1", "../go1.14"} {
+		assert.False(t, Valid(v), v)
+	}
+}
+
+func TestInstallFromFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, Archive("1.14.1"))
+	writeDist(t, archive, "go/bin/go", "go/bin/go.exe", "go/VERSION")
+	root := filepath.Join(dir, "toolchains")
+
+	assert.NoError(t, Install(root, "1.14.1", archive, ""))
+	assert.True(t, Installed(root, "go1.14.1"))
+	_, err := os.Stat(filepath.Join(root, "go1.14.1", "VERSION"))
+	assert.NoError(t, err)
+
+	list, err := List(root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go1.14.1"}, list)
+
+	assert.NoError(t, Remove(root, "1.14.1"))
+	assert.False(t, Installed(root, "1.14.1"))
+	assert.Equal(t, ErrNotInstalled, Remove(root, "1.14.1"))
+}
+
+func TestInstallFromMirror(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, Archive("1.14.2"))
+	writeDist(t, archive, "go/bin/go", "go/bin/go.exe")
+	sum := writeSum(t, archive)
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	root := filepath.Join(dir, "toolchains")
+
+	assert.NoError(t, Install(root, "go1.14.2", srv.URL, ""))
+	assert.True(t, Installed(root, "1.14.2"))
+
+	err := Install(root, "go1.14.3", srv.URL+"/", "")
+	assert.Error(t, err)
+	assert.False(t, Installed(root, "1.14.3"))
+
+	// a checksum that was given is used instead of the mirror's
+	assert.NoError(t, os.Remove(archive+".sha256"))
+	assert.Error(t, Install(root, "go1.14.2", srv.URL, ""))
+	assert.NoError(t, Install(root, "go1.14.2", srv.URL, strings.ToUpper(sum)))
+}
+
+func TestInstallChecksum(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, Archive("1.14.4"))
+	writeDist(t, archive, "go/bin/go", "go/bin/go.exe")
+	root := filepath.Join(dir, "toolchains")
+	wrong := strings.Repeat("0", 64)
+
+	for _, sum := range []string{wrong, "abc"} {
+		err := Install(root, "1.14.4", archive, sum)
+		assert.True(t, errors.Is(err, ErrChecksum), sum)
+		assert.False(t, Installed(root, "1.14.4"))
+	}
+
+	assert.NoError(t, ioutil.WriteFile(archive+".sha256", []byte(wrong+"\n"), 0644))
+	assert.True(t, errors.Is(Install(root, "1.14.4", archive, ""), ErrChecksum))
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	assert.True(t, errors.Is(Install(root, "1.14.4", srv.URL, ""), ErrChecksum))
+	assert.False(t, Installed(root, "1.14.4"))
+
+	writeSum(t, archive)
+	assert.NoError(t, Install(root, "1.14.4", srv.URL, ""))
+	assert.True(t, Installed(root, "1.14.4"))
+}
+
+func TestInstallInvalid(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "bad.tar.gz")
+	writeDist(t, archive, "go/README")
+	root := filepath.Join(dir, "toolchains")
+
+	assert.Equal(t, ErrNoDistribution, Install(root, "1.14", archive, ""))
+	assert.Equal(t, ErrInvalidVersion, Install(root, "latest", archive, ""))
+
+	writeDist(t, archive, "../evil")
+	assert.Error(t, Install(root, "1.14", archive, ""))
+	_, err := os.Stat(filepath.Join(root, "evil"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInstallSymlinks(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "links.tar.gz")
+	root := filepath.Join(dir, "toolchains")
+
+	for _, files := range [][]string{
+		{"go/bin/go", "go/etc -> /etc"},
+		{"go/bin/go", "go/up -> ../../.."},
+		{"go/bin/go", "go/here -> .", "go/here/up -> ../x"},
+		{"go/bin/go", "go/here -> .", "go/here/x/../../evil"},
+	} {
+		writeDist(t, archive, files...)
+		assert.Error(t, Install(root, "1.14", archive, ""), files[1])
+		assert.False(t, Installed(root, "1.14"))
+	}
+	_, err := os.Stat(filepath.Join(dir, "evil"))
+	assert.True(t, os.IsNotExist(err))
+
+	writeDist(t, archive, "go/bin/go", "go/bin/go.exe", "go/bin/gofmt -> go")
+	assert.NoError(t, Install(root, "1.14", archive, ""))
+	target, err := os.Readlink(filepath.Join(root, "go1.14", "bin", "gofmt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "go", target)
+}
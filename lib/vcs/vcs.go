@@ -0,0 +1,139 @@
+/*
+Copyright © 2020 Peter Magnusson <code@kmpm.se>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidURL - The string is not a repository url git understands
+	ErrInvalidURL = errors.New("invalid repository url")
+
+	// PublicHosts are shared by many owners, so the owner is part of the
+	// GOPRIVATE pattern for repositories on them
+	PublicHosts = []string{"github.com", "gitlab.com", "bitbucket.org", "codeberg.org"}
+
+	scpRe = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+)
+
+//Remote is a parsed repository url
+type Remote struct {
+	// Host without user and port, empty for local repositories
+	Host string
+	// Path on the host without a .git suffix, like owner/repo
+	Path string
+}
+
+//Parse understands the url forms git clone accepts, like
+//https://host/owner/repo.git, ssh://git@host:22/owner/repo,
+//git@host:owner/repo.git and local paths
+func Parse(rawurl string) (*Remote, error) {
+	if rawurl == "" {
+		return nil, ErrInvalidURL
+	}
+	var r Remote
+	if strings.Contains(rawurl, "://") {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rawurl)
+		}
+		if u.Scheme != "file" {
+			r.Host = u.Hostname()
+			if r.Host == "" {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rawurl)
+			}
+		}
+		r.Path = u.Path
+	} else if m := scpRe.FindStringSubmatch(rawurl); m != nil && len(m[1]) > 1 {
+		// a single letter before the colon is a windows drive
+		r.Host, r.Path = m[1], m[2]
+	} else {
+		r.Path = rawurl
+	}
+	r.Path = strings.TrimSuffix(strings.Trim(strings.Replace(r.Path, `\`, "/", -1), "/"), ".git")
+	if r.Path == "" || r.Name() == "." {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rawurl)
+	}
+	return &r, nil
+}
+
+//Name is the last element of the path, what git names the clone
+func (r *Remote) Name() string {
+	return path.Base(r.Path)
+}
+
+//Module returns the import path the repository most likely has
+func (r *Remote) Module() string {
+	if r.Host == "" {
+		return r.Name()
+	}
+	return r.Host + "/" + r.Path
+}
+
+//PrivatePattern returns the GOPRIVATE pattern covering the repository.
+//It is the host, or host/owner on the PublicHosts. Local repositories
+//have no pattern.
+func (r *Remote) PrivatePattern() string {
+	if r.Host == "" {
+		return ""
+	}
+	for _, h := range PublicHosts {
+		if strings.EqualFold(h, r.Host) {
+			return r.Host + "/" + strings.SplitN(r.Path, "/", 2)[0]
+		}
+	}
+	return r.Host
+}
+
+//AddPattern appends pattern to the comma separated list unless it is
+//already there
+func AddPattern(list, pattern string) string {
+	if pattern == "" {
+		return list
+	}
+	for _, p := range strings.Split(list, ",") {
+		if strings.TrimSpace(p) == pattern {
+			return list
+		}
+	}
+	if strings.TrimSpace(list) == "" {
+		return pattern
+	}
+	return list + "," + pattern
+}
+
+//Clone runs git clone of rawurl into dest, checking out branch if it is
+//not empty
+func Clone(rawurl, dest, branch string) error {
+	args := []string{"clone"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, "--", rawurl, dest)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clone: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		url, host, path, private string
+	}{
+		{"https://github.com/kmpm/gopr.git", "github.com", "kmpm/gopr", "github.com/kmpm"},
+		{"https://user@GitLab.com/group/sub/repo", "GitLab.com", "group/sub/repo", "GitLab.com/group"},
+		{"ssh://git@git.example.com:2222/team/repo.git", "git.example.com", "team/repo", "git.example.com"},
+		{"git@github.com:kmpm/gopr.git", "github.com", "kmpm/gopr", "github.com/kmpm"},
+		{"example.com:repo", "example.com", "repo", "example.com"},
+		{"/srv/git/repo.git", "", "srv/git/repo", ""},
+		{"file:///srv/git/repo.git/", "", "srv/git/repo", ""},
+		{"../repo", "", "../repo", ""},
+		{`C:\src\repo.git`, "", "C:/src/repo", ""},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.url)
+		if assert.NoError(t, err, tt.url) {
+			assert.Equal(t, tt.host, r.Host, tt.url)
+			assert.Equal(t, tt.path, r.Path, tt.url)
+			assert.Equal(t, tt.private, r.PrivatePattern(), tt.url)
+		}
+	}
+
+	for _, u := range []string{"", "https:///repo", "https://example.com/", "/"} {
+		_, err := Parse(u)
+		assert.Error(t, err, u)
+	}
+
+	r, _ := Parse("git@github.com:kmpm/gopr.git")
+	assert.Equal(t, "gopr", r.Name())
+	assert.Equal(t, "github.com/kmpm/gopr", r.Module())
+}
+
+func TestAddPattern(t *testing.T) {
+	assert.Equal(t, "example.com", AddPattern("", "example.com"))
+	assert.Equal(t, "a.com,example.com", AddPattern("a.com", "example.com"))
+	assert.Equal(t, "a.com, example.com", AddPattern("a.com, example.com", "example.com"))
+	assert.Equal(t, "a.com", AddPattern("a.com", ""))
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=gopr", "GIT_AUTHOR_EMAIL=gopr@example.com",
+		"GIT_COMMITTER_NAME=gopr", "GIT_COMMITTER_EMAIL=gopr@example.com")
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, err := ioutil.TempDir("", "vcs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	work := filepath.Join(dir, "work")
+	assert.NoError(t, os.MkdirAll(work, 0755))
+	git(t, work, "init", "-q")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(work, "go.mod"), []byte("module example.com/repo\n"), 0644))
+	git(t, work, "add", "go.mod")
+	git(t, work, "commit", "-q", "-m", "initial")
+	git(t, work, "checkout", "-q", "-b", "feature")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(work, "feature"), nil, 0644))
+	git(t, work, "add", "feature")
+	git(t, work, "commit", "-q", "-m", "feature")
+	bare := filepath.Join(dir, "repo.git")
+	git(t, dir, "clone", "-q", "--bare", work, bare)
+
+	dest := filepath.Join(dir, "src", "repo")
+	assert.NoError(t, Clone(bare, dest, "feature"))
+	_, err = os.Stat(filepath.Join(dest, "feature"))
+	assert.NoError(t, err)
+
+	assert.Error(t, Clone(bare, dest, ""), "destination exists")
+	assert.Error(t, Clone(bare, filepath.Join(dir, "other"), "missing"))
+}